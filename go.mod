@@ -4,6 +4,7 @@ go 1.17
 
 require (
 	github.com/cheynewallace/tabby v1.1.1
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/nu7hatch/gouuid v0.0.0-20131221200532-179d4d0c4d8d
 	github.com/spf13/cobra v1.3.0
 	github.com/spf13/viper v1.10.0
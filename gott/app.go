@@ -0,0 +1,138 @@
+package gott
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// App bundles everything a gott command needs: the config, the database
+// and the clock. Create it with NewApp, Open it before using the database
+// and Close it afterwards to save the changes.
+type App struct {
+	Config   Config
+	Database Database
+	Journal  *Journal
+	Clock    Clock
+	In       io.Reader
+	Out      io.Writer
+
+	lock     *fileLock
+	readOnly bool
+	// inReader buffers In for confirm
+	inReader *bufio.Reader
+	inSource io.Reader
+}
+
+// NewApp creates the app for config. It does not touch the database file
+// before Open is called.
+func NewApp(config Config) (*App, error) {
+	app := &App{
+		Config: config,
+		Clock:  SystemClock{},
+		In:     os.Stdin,
+		Out:    os.Stdout,
+	}
+	db, err := NewDatabase(config.DatabaseName, config.DatabaseType, app.calendar(), config.UDAs)
+	if err != nil {
+		return nil, err
+	}
+	// only the json database is replaced as a whole and keeps backups
+	if j, ok := db.(*DatabaseJson); ok {
+		j.backups = config.Backups
+	}
+	app.Journal = NewJournal(db, config.DatabaseName+journalSuffix, "", app.clock())
+	app.Database = app.Journal
+	return app, nil
+}
+
+// clock follows changes of a.Clock, so it can be replaced after the database
+// was created. The time is in the configured location.
+func (a *App) clock() Clock {
+	return clockFunc(func() time.Time {
+		return a.local(a.Clock.Now())
+	})
+}
+
+// calendar buckets the times of clock into the configured working days.
+func (a *App) calendar() Calendar {
+	return Calendar{Clock: a.clock(), DayBoundary: a.Config.DayBoundary}
+}
+
+// newInterval lexes a new interval from args with the declared udas and sets
+// the defaults of the missing udas.
+func (a *App) newInterval(args []string) (Interval, error) {
+	interval := NewInterval(nil)
+	if err := lexInterval(args, &interval, a.Config.UDAs); err != nil {
+		return interval, err
+	}
+	interval.Raw = strings.Join(args, " ")
+	a.Config.UDAs.setDefaults(&interval)
+	return interval, nil
+}
+
+// local converts t to the configured location.
+func (a *App) local(t time.Time) time.Time {
+	if a.Config.Location == nil {
+		return t
+	}
+	return t.In(a.Config.Location)
+}
+
+// Open locks and loads the database. Read-only access only takes a shared
+// lock and the database is not saved on Close. The lock is held until Close
+// is called or the process ends.
+func (a *App) Open(readOnly bool) error {
+	lock, err := lockDatabase(a.Config.DatabaseName, !readOnly, a.Config.LockTimeout)
+	if err != nil {
+		return err
+	}
+	a.lock = lock
+	a.readOnly = readOnly
+	if err := a.Database.Load(); err != nil {
+		a.lock.Unlock()
+		a.lock = nil
+		return err
+	}
+	return nil
+}
+
+// Discard makes Close skip saving the database.
+func (a *App) Discard() {
+	a.readOnly = true
+}
+
+// Close saves the database unless it was opened read-only, records the
+// changes in the journal and releases the lock. Journal failures after a
+// successful save are returned as *JournalError.
+func (a *App) Close() error {
+	if a.lock == nil {
+		// never opened. nothing to save
+		return nil
+	}
+	defer func() {
+		a.lock.Unlock()
+		a.lock = nil
+	}()
+	if a.readOnly {
+		// databases with transactions discard the changes
+		if c, ok := a.Journal.Database.(io.Closer); ok {
+			return c.Close()
+		}
+		return nil
+	}
+	// saving may close the database, so the changes are taken before
+	entry, changed := a.Journal.Pending()
+	if err := a.Database.Save(); err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	if err := a.Journal.CommitEntry(entry); err != nil {
+		return &JournalError{Err: err}
+	}
+	return nil
+}
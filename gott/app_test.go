@@ -0,0 +1,362 @@
+package gott
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestApp(t *testing.T) (*App, *bytes.Buffer) {
+	config := DefaultConfig()
+	config.DatabaseName = filepath.Join(t.TempDir(), "db.json")
+	app, err := NewApp(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	app.Out = out
+	return app, out
+}
+
+// runApp runs the gott command line args on app like Execute does.
+func runApp(t *testing.T, app *App, args ...string) {
+	cmd := NewRootCmd(app)
+	cmd.SetArgs(args)
+	assert.NoError(t, cmd.Execute())
+	assert.NoError(t, app.Close())
+}
+
+func TestAppCommands(t *testing.T) {
+	app, out := newTestApp(t)
+
+	runApp(t, app, "start", "writing", "docs", "proj:gott.docs", "+docs")
+	assert.Contains(t, out.String(), "tracking writing docs -- proj:gott.docs -- docs")
+
+	runApp(t, app, "stop")
+	runApp(t, app, "track", "2022-01-14", "3h", "--", "bake", "a", "cake")
+
+	out.Reset()
+	runApp(t, app, "summary", "2022-01-14")
+	assert.Contains(t, out.String(), "bake a cake")
+	assert.Contains(t, out.String(), "03:00")
+
+	// a new app sees the saved data
+	reopened, _ := NewApp(app.Config)
+	assert.NoError(t, reopened.Open(true))
+	assert.Equal(t, 2, reopened.Database.Count())
+	assert.NoError(t, reopened.Close())
+}
+
+func TestAppTrackTimes(t *testing.T) {
+	app, out := newTestApp(t)
+
+	runApp(t, app, "track", "2022-01-14", "09:30-11:15", "--", "meeting")
+	runApp(t, app, "track", "--force", "2022-01-14", "11:00", "for", "30m", "--", "call")
+
+	out.Reset()
+	runApp(t, app, "summary", "2022-01-14")
+	assert.Contains(t, out.String(), "09:30  11:15  01:45")
+	assert.Contains(t, out.String(), "11:00  11:30  00:30")
+	assert.Contains(t, out.String(), "day =  02:15")
+}
+
+func TestAppStartStopAt(t *testing.T) {
+	app, out := newTestApp(t)
+	now := "--now=2022-01-14 12:00"
+
+	runApp(t, app, now, "track", "2022-01-14", "08:00-09:00", "--", "standup")
+	runApp(t, app, now, "start", "--at", "09:10", "writing", "docs")
+	assert.Regexp(t, `Started +01-14 09:10`, out.String())
+
+	out.Reset()
+	runApp(t, app, now, "stop", "15m", "ago")
+	assert.Regexp(t, `Stopped +01-14 11:45`, out.String())
+	assert.Regexp(t, `Current \(mins\) +02:35`, out.String())
+
+	out.Reset()
+	runApp(t, app, now, "summary")
+	assert.Contains(t, out.String(), "09:10  11:45  02:35")
+
+	current, _ := app.Database.Latest()
+	assert.Equal(t, "writing docs", current.Annotation)
+}
+
+func TestCheckStart(t *testing.T) {
+	app, _ := newTestApp(t)
+	assert.NoError(t, app.Open(false))
+	defer app.Discard()
+	cal := app.calendar()
+	day := time.Date(2022, 1, 14, 0, 0, 0, 0, time.Local)
+	now := cal.At(day, 12, 0)
+
+	meeting := NewInterval([]string{"meeting"})
+	meeting.Begin = cal.At(day, 9, 0)
+	meeting.End = cal.At(day, 10, 0)
+	app.Database.Append(meeting)
+
+	// begins before the meeting ended
+	overlapping, err := checkStart(app.Database, NewInterval(nil), cal.At(day, 9, 30), now)
+	assert.NoError(t, err)
+	if assert.Len(t, overlapping, 1) {
+		assert.Equal(t, "meeting", overlapping[0].Annotation)
+	}
+
+	app.Database.Start(NewInterval([]string{"running"}), cal.At(day, 10, 30))
+
+	// stops the running interval before it began
+	_, err = checkStart(app.Database, NewInterval(nil), cal.At(day, 10, 15), now)
+	assert.Error(t, err)
+
+	overlapping, err = checkStart(app.Database, NewInterval(nil), cal.At(day, 11, 0), now)
+	assert.NoError(t, err)
+	assert.Empty(t, overlapping)
+
+	_, err = checkStop(app.Database, cal.At(day, 10, 0), now)
+	assert.Error(t, err)
+}
+
+func TestAppHandles(t *testing.T) {
+	app, out := newTestApp(t)
+	now := "--now=2022-01-14 12:00"
+
+	runApp(t, app, now, "track", "2022-01-14", "08:00-09:00", "--", "standup")
+	runApp(t, app, now, "track", "2022-01-14", "09:00-10:00", "--", "meeting", "+call")
+	runApp(t, app, now, "track", "2022-01-14", "10:00-11:00", "--", "typo")
+
+	out.Reset()
+	runApp(t, app, now, "summary")
+	assert.Regexp(t, `@3 +08:00`, out.String())
+	assert.Regexp(t, `@1 +10:00`, out.String())
+
+	runApp(t, app, now, "delete", "@1")
+	runApp(t, app, now, "annotate", "@2", "daily", "standup")
+	runApp(t, app, now, "continue", "@1")
+
+	out.Reset()
+	runApp(t, app, now, "summary")
+	assert.NotContains(t, out.String(), "typo")
+	assert.Contains(t, out.String(), "daily standup")
+	current, found := app.Database.GetCurrent()
+	assert.True(t, found)
+	assert.Equal(t, "meeting", current.Annotation)
+	assert.Equal(t, []string{"call"}, current.Tags)
+}
+
+func TestAppModify(t *testing.T) {
+	app, out := newTestApp(t)
+	now := "--now=2022-01-14 18:00"
+
+	for _, hour := range []string{"08", "09", "10", "11"} {
+		runApp(t, app, now, "track", "2022-01-14", hour+":00-"+hour+":30", "--", "work", "proj:gott", "+draft")
+	}
+
+	out.Reset()
+	runApp(t, app, now, "modify", "@1", "--end", "11:45", "--dry-run", "--", "-draft")
+	assert.Contains(t, out.String(), `tags: "draft" -> ""`)
+	assert.Contains(t, out.String(), "dry run")
+	latest, _ := resolveInterval(app.Database, "@1")
+	assert.Equal(t, []string{"draft"}, latest.Tags)
+
+	runApp(t, app, now, "modify", "@1", "@2", "--", "-draft", "+billable")
+	latest, _ = resolveInterval(app.Database, "@2")
+	assert.Equal(t, []string{"billable"}, latest.Tags)
+
+	// more than bulk intervals need confirmation
+	app.In = strings.NewReader("no\n")
+	runApp(t, app, now, "modify", "proj:gott", "proj:gott.docs")
+	assert.Contains(t, out.String(), "modify 4 intervals? (yes/no)")
+	intervals, _ := app.Database.Filter([]string{"proj:gott.docs"})
+	assert.Empty(t, intervals)
+
+	app.In = strings.NewReader("yes\n")
+	runApp(t, app, now, "modify", "proj:gott", "proj:gott.docs")
+	intervals, _ = app.Database.Filter([]string{"proj:gott.docs"})
+	assert.Len(t, intervals, 4)
+}
+
+func TestAppAnnotateMerges(t *testing.T) {
+	app, _ := newTestApp(t)
+	now := "--now=2022-01-14 12:00"
+
+	runApp(t, app, now, "start", "writing", "docs", "proj:gott", "+docs")
+	runApp(t, app, now, "annotate", "+billable")
+	runApp(t, app, now, "annotate", "--", "-docs", "ref:ID-1")
+
+	current, _ := app.Database.GetCurrent()
+	assert.Equal(t, "writing docs", current.Annotation)
+	assert.Equal(t, []string{"billable"}, current.Tags)
+	assert.Equal(t, "ID-1", current.Ref)
+	assert.Equal(t, "writing docs proj:gott +billable ref:ID-1", current.Raw)
+
+	// continue reproduces the merged interval
+	runApp(t, app, "--now=2022-01-14 13:00", "stop")
+	runApp(t, app, "--now=2022-01-14 14:00", "continue")
+	current, _ = app.Database.GetCurrent()
+	assert.Equal(t, "writing docs", current.Annotation)
+	assert.Equal(t, "gott", current.Project)
+	assert.Equal(t, []string{"billable"}, current.Tags)
+
+	runApp(t, app, now, "annotate", "--replace", "review")
+	current, _ = app.Database.GetCurrent()
+	assert.Equal(t, "review", current.Annotation)
+	assert.Empty(t, current.Project)
+	assert.Empty(t, current.Tags)
+	assert.Equal(t, "review", current.Raw)
+}
+
+func TestAppEdit(t *testing.T) {
+	app, out := newTestApp(t)
+	now := "--now=2022-01-14 18:00"
+	runApp(t, app, now, "track", "2022-01-14", "09:00-10:00", "--", "fix", "proj:gott.docs", "+docs")
+
+	app.Config.Editor = "true"
+	runApp(t, app, now, "edit")
+	assert.Contains(t, out.String(), "file unchanged")
+
+	// editors with args, the change needs confirmation
+	app.Config.Editor = `sed -i -e 's/ 10:00 / 11:30 /'`
+	app.In = strings.NewReader("no\n")
+	runApp(t, app, now, "edit")
+	assert.Contains(t, out.String(), `end: "2022-01-14 10:00:00" -> "2022-01-14 11:30:00"`)
+	assert.Contains(t, out.String(), "apply 1 changes? (yes/no)")
+	i, _ := resolveInterval(app.Database, "@1")
+	assert.Equal(t, 10, app.local(i.End).Hour())
+
+	runApp(t, app, now, "edit", "--yes")
+	i, _ = resolveInterval(app.Database, "@1")
+	assert.Equal(t, 11, app.local(i.End).Hour())
+	assert.Equal(t, "gott.docs", i.Project)
+	assert.Equal(t, []string{"docs"}, i.Tags)
+
+	// an added running interval becomes the current one
+	app.Config.Editor = `sed -i -e '$a - 2022-01-14 17:00 - - review'`
+	runApp(t, app, now, "edit", "--yes")
+	current, found := app.Database.GetCurrent()
+	if assert.True(t, found) {
+		assert.Equal(t, "review", current.Annotation)
+	}
+
+	assert.Error(t, runEditor("false", filepath.Join(t.TempDir(), "file")))
+}
+
+func TestAppUDAs(t *testing.T) {
+	config := DefaultConfig()
+	config.DatabaseName = filepath.Join(t.TempDir(), "db.json")
+	config.UDAs = testUDAs
+	app, err := NewApp(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	app.Out = out
+	now := "--now=2022-01-14 12:00"
+
+	runApp(t, app, now, "start", "review", "estimate:2h")
+	assert.Contains(t, out.String(), "tracking review -- client:acme -- estimate:2h0m0s")
+	runApp(t, app, "--now=2022-01-14 13:00", "stop")
+	runApp(t, app, now, "track", "2022-01-14", "08:00-09:00", "--", "plan", "client:initech", "points:3")
+
+	out.Reset()
+	runApp(t, app, now, "summary", "--uda", "client,estimate", "--", ":today", "client:acme")
+	assert.Regexp(t, `CLIENT\s+ESTIMATE\s+ANNOTATION`, out.String())
+	assert.Regexp(t, `acme\s+2h0m0s\s+review`, out.String())
+	assert.NotContains(t, out.String(), "plan")
+
+	// continue keeps the udas
+	runApp(t, app, "--now=2022-01-14 14:00", "continue", "@2")
+	current, _ := app.Database.GetCurrent()
+	assert.Equal(t, map[string]interface{}{"client": "initech", "points": 3.0}, current.UDA)
+}
+
+func TestAppDbConvert(t *testing.T) {
+	dir := t.TempDir()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "db_v0.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := filepath.Join(dir, "source.json")
+	assert.NoError(t, ioutil.WriteFile(source, data, 0644))
+
+	config := DefaultConfig()
+	config.DatabaseName = filepath.Join(dir, "db.sqlite")
+	app, err := NewApp(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	app.Out = out
+	runApp(t, app, "db", "convert", source)
+	assert.Contains(t, out.String(), "copied 2 of 2 intervals")
+
+	// the source is neither saved nor migrated
+	converted, _ := ioutil.ReadFile(source)
+	assert.Equal(t, string(data), string(converted))
+
+	// the same for a sqlite source
+	data, _ = ioutil.ReadFile(config.DatabaseName)
+	other, out := newTestApp(t)
+	runApp(t, other, "db", "convert", config.DatabaseName)
+	assert.Contains(t, out.String(), "copied 2 of 2 intervals")
+	converted, _ = ioutil.ReadFile(config.DatabaseName)
+	assert.Equal(t, data, converted)
+	current, found := other.Database.GetCurrent()
+	if assert.True(t, found) {
+		assert.Equal(t, "gott.docs", current.Project)
+	}
+}
+
+func TestAppDurationOnlyWestOfUTC(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "db_v0.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, out := newTestApp(t)
+	assert.NoError(t, ioutil.WriteFile(app.Config.DatabaseName, data, 0644))
+	app.Config.Location = newYork
+
+	// stored at midnight UTC, it was on the evening before in new york
+	runApp(t, app, "summary", "2022-01-14")
+	assert.Contains(t, out.String(), "bake a cake")
+	out.Reset()
+	runApp(t, app, "summary", "2022-01-13")
+	assert.NotContains(t, out.String(), "bake a cake")
+}
+
+func TestAppModifySqliteTimezone(t *testing.T) {
+	// the configured timezone differs from the zone sqlite returns times in
+	zone := "America/New_York"
+	if time.Local.String() == zone {
+		zone = "Asia/Tokyo"
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		t.Skip(err)
+	}
+	config := DefaultConfig()
+	config.DatabaseName = filepath.Join(t.TempDir(), "db.sqlite")
+	config.Location = loc
+	app, err := NewApp(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Out = &bytes.Buffer{}
+	now := "--now=2022-01-14 22:00"
+	runApp(t, app, now, "track", "2022-01-14", "20:00-21:00", "--", "review")
+	runApp(t, app, now, "modify", "@1", "--begin", "19:00")
+
+	assert.NoError(t, app.Open(true))
+	defer app.Close()
+	i, err := resolveInterval(app.Database, "@1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.Date(2022, 1, 14, 19, 0, 0, 0, loc), i.Begin.In(loc))
+	}
+}
@@ -0,0 +1,121 @@
+package gott
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	backupSuffix = ".bak"
+	// defaultBackups is the number of backups kept of the database
+	defaultBackups = 3
+)
+
+// writeFileAtomic replaces filename with data without ever leaving a
+// truncated file behind. The data is written and synced to a temp file in the
+// same directory which is renamed into place afterwards. The previous
+// versions of the file are kept as filename.bak.1 until filename.bak.N for
+// backups N, see backupFile.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode, backups int) (err error) {
+	dir := filepath.Dir(filename)
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating temp file: %s", err.Error())
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return fmt.Errorf("error writing temp file: %s", err.Error())
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("error syncing temp file: %s", err.Error())
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("error closing temp file: %s", err.Error())
+	}
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("error setting file mode: %s", err.Error())
+	}
+
+	if err = backupFile(filename, backups); err != nil {
+		return fmt.Errorf("error creating backup: %s", err.Error())
+	}
+	if err = os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("error replacing %s: %s", filename, err.Error())
+	}
+
+	// persist the rename. not every platform supports syncing a directory,
+	// so errors are ignored here.
+	if d, dirErr := os.Open(dir); dirErr == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// backupName returns the name of the nth backup of filename.
+func backupName(filename string, n int) string {
+	return fmt.Sprintf("%s%s.%d", filename, backupSuffix, n)
+}
+
+// backupFile keeps the current version of filename as filename.bak.1 and
+// shifts the older backups up to filename.bak.N for n backups, the oldest
+// one is removed. Without backups nothing is kept. The original file stays in
+// place until it is replaced.
+func backupFile(filename string, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err := os.Remove(backupName(filename, n)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for k := n - 1; k > 0; k-- {
+		if err := os.Rename(backupName(filename, k), backupName(filename, k+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	backup := backupName(filename, 1)
+	if err := os.Link(filename, backup); err == nil {
+		return nil
+	}
+	// hard links are not supported everywhere. copy instead.
+	return copyFile(filename, backup)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
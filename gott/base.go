@@ -1,10 +1,11 @@
 package gott
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
-
-	"github.com/spf13/viper"
+	"strings"
 )
 
 const (
@@ -15,31 +16,50 @@ const (
 	timeFormat          = "15:04"
 )
 
-var database Database
-
-func init() {
+// annotationReadOnly marks commands which do not modify the database. They
+// only take a shared lock and do not save the database.
+const annotationReadOnly = "readonly"
 
-	viper.SetConfigName("gottrc")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("$XDG_CONFIG_HOME/gott/")
+var readOnly = map[string]string{annotationReadOnly: "true"}
 
-	viper.SetDefault(ConfDatabaseName, "db.json")
+// annotationReadOnlyUnless names a bool flag. Commands with it are read-only
+// unless the flag is set, like doctor without --fix.
+const annotationReadOnlyUnless = "readonly-unless"
 
-	viper.AutomaticEnv()
+func Execute() {
+	os.Exit(execute(os.Args[1:], os.Stdout, os.Stderr))
+}
 
-	if err := viper.ReadInConfig(); err != nil {
-		fmt.Println("[WARNING] ", err.Error())
+// execute runs gott with args and returns the exit code. Only the output of
+// the command goes to stdout, so exports can be redirected into files.
+func execute(args []string, stdout, stderr io.Writer) int {
+	config, err := ReadConfig()
+	if err != nil {
+		fmt.Fprintln(stderr, "[WARNING] ", err.Error())
 	}
 
-	databaseName := viper.GetString(ConfDatabaseName)
-	database = NewDatabaseJson(databaseName)
-	database.Load()
-}
+	app, err := NewApp(config)
+	if err != nil {
+		fmt.Fprintln(stderr, "ERROR:", err.Error())
+		return 1
+	}
+	app.Out = stdout
+	app.Journal.Command = strings.Join(append([]string{"gott"}, args...), " ")
 
-func Execute() {
-	defer database.Save()
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		os.Exit(1)
+	cmd := NewRootCmd(app)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 1
+	}
+	if err := app.Close(); err != nil {
+		var journalErr *JournalError
+		if errors.As(err, &journalErr) {
+			fmt.Fprintln(stderr, "ERROR: the database was saved, but writing the journal failed:", err.Error())
+		} else {
+			fmt.Fprintln(stderr, "ERROR: saving database failed:", err.Error())
+		}
+		return 1
 	}
+	return 0
 }
@@ -0,0 +1,70 @@
+package gott
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteWithoutConfig(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// no gottrc in the working directory or the config home
+	assert.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("DATABASENAME", filepath.Join(dir, "db.json"))
+	t.Setenv("TIMEZONE", "UTC")
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, execute(strings.Fields("track 2022-01-14 09:00-10:00 -- proj:gott writing"), &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "[WARNING]")
+
+	for format, check := range map[string]func(string) error{
+		ExportFormatJSON: func(out string) error {
+			_, err := readJSON(strings.NewReader(out))
+			return err
+		},
+		ExportFormatCSV: func(out string) error {
+			f, _ := newCSVFormat(DefaultCSVColumns, nil, ",", nil)
+			_, err := readCSV(strings.NewReader(out), f)
+			return err
+		},
+		ExportFormatTimew: func(out string) error {
+			var exported []map[string]interface{}
+			return json.Unmarshal([]byte(out), &exported)
+		},
+	} {
+		stdout.Reset()
+		stderr.Reset()
+		assert.Equal(t, 0, execute([]string{"export", "--format", format}, &stdout, &stderr), format)
+		assert.Contains(t, stderr.String(), "[WARNING]", format)
+		assert.NotContains(t, stdout.String(), "WARNING", format)
+		assert.NoError(t, check(stdout.String()), format)
+	}
+}
+
+func TestExecuteJournalError(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("DATABASENAME", filepath.Join(dir, "db.json"))
+	// the journal can't be written where a directory is
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "db.json"+journalSuffix), 0755))
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 1, execute(strings.Fields("track 2022-01-14 2h"), &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "writing the journal failed")
+	assert.NotContains(t, stderr.String(), "saving database failed")
+
+	db := NewDatabaseJson(filepath.Join(dir, "db.json"), Calendar{Clock: SystemClock{}})
+	assert.NoError(t, db.Load())
+	assert.Equal(t, 1, db.Count())
+}
@@ -0,0 +1,101 @@
+package gott
+
+import (
+	"fmt"
+	"time"
+)
+
+// Calendar days, weeks and months are bucketed in the location of the given
+// time. Use time.Date instead of Truncate, so days with DST transitions have
+// 23 or 25 hours.
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns the start of the monday of the week of t.
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return startOfDay(t).AddDate(0, 0, -offset)
+}
+
+func startOfMonth(t time.Time) time.Time {
+	y, m, _ := t.Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
+
+// atTimeOfDay returns the time hour:minute on the day of t.
+func atTimeOfDay(t time.Time, hour, minute int) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, hour, minute, 0, 0, t.Location())
+}
+
+// Calendar buckets times into working days. A working day starts at
+// DayBoundary after midnight, so work past midnight still counts to the
+// previous day. It uses the location of the times given to it.
+type Calendar struct {
+	Clock
+	// DayBoundary is the time of day the working day starts
+	DayBoundary time.Duration
+}
+
+func (c Calendar) boundary() (hour, minute int) {
+	return int(c.DayBoundary / time.Hour), int(c.DayBoundary % time.Hour / time.Minute)
+}
+
+// DayStart returns the start of the working day on the date of day.
+func (c Calendar) DayStart(day time.Time) time.Time {
+	h, m := c.boundary()
+	return atTimeOfDay(day, h, m)
+}
+
+// StartOfDay returns the start of the working day t belongs to.
+func (c Calendar) StartOfDay(t time.Time) time.Time {
+	start := c.DayStart(t)
+	if t.Before(start) {
+		start = c.DayStart(startOfDay(t).AddDate(0, 0, -1))
+	}
+	return start
+}
+
+// Day returns the midnight of the date of the working day t belongs to.
+func (c Calendar) Day(t time.Time) time.Time {
+	return startOfDay(c.StartOfDay(t))
+}
+
+// EndOfDay returns the start of the working day after the one t belongs to.
+func (c Calendar) EndOfDay(t time.Time) time.Time {
+	return c.DayStart(c.Day(t).AddDate(0, 0, 1))
+}
+
+// StartOfWeek returns the start of the monday of the working day t belongs
+// to.
+func (c Calendar) StartOfWeek(t time.Time) time.Time {
+	return c.DayStart(startOfWeek(c.Day(t)))
+}
+
+// StartOfMonth returns the start of the first day of the month of the working
+// day t belongs to.
+func (c Calendar) StartOfMonth(t time.Time) time.Time {
+	return c.DayStart(startOfMonth(c.Day(t)))
+}
+
+// At returns hour:minute of the working day on the date of day. Times before
+// the day boundary are on the next date.
+func (c Calendar) At(day time.Time, hour, minute int) time.Time {
+	t := atTimeOfDay(day, hour, minute)
+	if t.Before(c.DayStart(day)) {
+		t = atTimeOfDay(startOfDay(day).AddDate(0, 0, 1), hour, minute)
+	}
+	return t
+}
+
+// parseDayBoundary parses the dayboundary config in the format HH:MM.
+func parseDayBoundary(value string) (time.Duration, error) {
+	t, err := time.Parse(timeFormat, value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %s. use the format HH:MM", ConfDayBoundary, value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
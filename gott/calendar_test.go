@@ -0,0 +1,125 @@
+package gott
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testZones = []string{"UTC", "Europe/Berlin", "America/New_York", "Asia/Kolkata", "Pacific/Auckland"}
+
+func loadZone(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestDayBoundariesInZones(t *testing.T) {
+	for _, zone := range testZones {
+		t.Run(zone, func(t *testing.T) {
+			loc := loadZone(t, zone)
+			now := time.Date(2022, 1, 14, 23, 45, 0, 0, loc)
+			db := NewDatabaseJson("", Calendar{Clock: FixedClock{Time: now}})
+
+			late := NewInterval([]string{"late"})
+			late.Begin = time.Date(2022, 1, 14, 23, 30, 0, 0, loc)
+			late.End = now
+			db.Append(late)
+
+			early := NewInterval([]string{"early"})
+			early.Begin = time.Date(2022, 1, 14, 0, 0, 0, 0, loc)
+			early.End = early.Begin.Add(time.Hour)
+			db.Append(early)
+
+			yesterday := NewInterval([]string{"yesterday"})
+			yesterday.Begin = time.Date(2022, 1, 13, 23, 59, 0, 0, loc)
+			yesterday.End = early.Begin
+			db.Append(yesterday)
+
+			today, _ := db.Filter([]string{KeyToday})
+			assert.Len(t, today, 2)
+			date, _ := db.Filter([]string{"2022-01-14"})
+			assert.Len(t, date, 2)
+			before, _ := db.Filter([]string{KeyYesterday})
+			if assert.Len(t, before, 1) {
+				assert.Equal(t, "yesterday", before[0].Annotation)
+			}
+
+			tracked := &Interval{}
+			assert.NoError(t, lexTrack([]string{KeyToday, "1h"}, tracked, Calendar{Clock: FixedClock{Time: now}}, nil))
+			assert.Equal(t, early.Begin, tracked.Begin)
+		})
+	}
+}
+
+func TestDSTDays(t *testing.T) {
+	loc := loadZone(t, "Europe/Berlin")
+
+	// 23 hour day
+	spring := time.Date(2022, 3, 27, 23, 30, 0, 0, loc)
+	assert.Equal(t, time.Date(2022, 3, 27, 0, 0, 0, 0, loc), startOfDay(spring))
+	assert.Equal(t, 23*time.Hour, startOfDay(spring).AddDate(0, 0, 1).Sub(startOfDay(spring)))
+
+	// 25 hour day
+	autumn := time.Date(2022, 10, 30, 23, 30, 0, 0, loc)
+	assert.Equal(t, 25*time.Hour, startOfDay(autumn).AddDate(0, 0, 1).Sub(startOfDay(autumn)))
+
+	// the week of the autumn transition starts on monday midnight
+	assert.Equal(t, time.Date(2022, 10, 24, 0, 0, 0, 0, loc), startOfWeek(autumn))
+	assert.Equal(t, time.Date(2022, 10, 1, 0, 0, 0, 0, loc), startOfMonth(autumn))
+
+	db := NewDatabaseJson("", Calendar{Clock: FixedClock{Time: spring}})
+	i := NewInterval([]string{"late"})
+	i.Begin = spring
+	i.End = spring.Add(15 * time.Minute)
+	db.Append(i)
+	result, _ := db.Filter([]string{"2022-03-27"})
+	assert.Len(t, result, 1)
+	result, _ = db.Filter([]string{"2022-03-28"})
+	assert.Len(t, result, 0)
+}
+
+func TestDayBoundary(t *testing.T) {
+	loc := loadZone(t, "Europe/Berlin")
+	// still the working day of the 14th
+	now := time.Date(2022, 1, 15, 3, 0, 0, 0, loc)
+	cal := Calendar{Clock: FixedClock{Time: now}, DayBoundary: 4 * time.Hour}
+
+	assert.Equal(t, time.Date(2022, 1, 14, 4, 0, 0, 0, loc), cal.StartOfDay(now))
+	assert.Equal(t, time.Date(2022, 1, 14, 0, 0, 0, 0, loc), cal.Day(now))
+	assert.Equal(t, time.Date(2022, 1, 15, 4, 0, 0, 0, loc), cal.EndOfDay(now))
+	assert.Equal(t, time.Date(2022, 1, 10, 4, 0, 0, 0, loc), cal.StartOfWeek(now))
+	assert.Equal(t, time.Date(2022, 1, 15, 2, 0, 0, 0, loc), cal.At(cal.Day(now), 2, 0))
+	assert.Equal(t, time.Date(2022, 1, 14, 22, 0, 0, 0, loc), cal.At(cal.Day(now), 22, 0))
+
+	db := NewDatabaseJson("", cal)
+	night := NewInterval([]string{"night", "shift"})
+	night.Begin = time.Date(2022, 1, 14, 22, 0, 0, 0, loc)
+	night.End = time.Date(2022, 1, 15, 2, 0, 0, 0, loc)
+	db.Append(night)
+	after := NewInterval([]string{"after", "midnight"})
+	after.Begin = night.End
+	after.End = time.Date(2022, 1, 15, 2, 30, 0, 0, loc)
+	db.Append(after)
+
+	today, _ := db.Filter([]string{KeyToday})
+	assert.Len(t, today, 2)
+	date, _ := db.Filter([]string{"2022-01-14"})
+	assert.Len(t, date, 2)
+	date, _ = db.Filter([]string{"2022-01-15"})
+	assert.Len(t, date, 0)
+
+	tracked := &Interval{}
+	assert.NoError(t, lexTrack([]string{KeyToday, "1h"}, tracked, cal, nil))
+	assert.Equal(t, time.Date(2022, 1, 14, 4, 0, 0, 0, loc), tracked.Begin)
+
+	boundary, err := parseDayBoundary("04:00")
+	assert.NoError(t, err)
+	assert.Equal(t, 4*time.Hour, boundary)
+	_, err = parseDayBoundary("4h")
+	assert.Error(t, err)
+}
@@ -0,0 +1,58 @@
+package gott
+
+import (
+	"fmt"
+	"time"
+)
+
+// EnvNow overrides the current time like the --now flag.
+const EnvNow = "GOTT_NOW"
+
+var nowFormats = []string{
+	time.RFC3339,
+	datetimeFormat,
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	dateFormat,
+}
+
+// Clock returns the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the wall clock.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FixedClock always returns the same time. It is used to run gott "as of" a
+// given time.
+type FixedClock struct {
+	Time time.Time
+}
+
+func (c FixedClock) Now() time.Time {
+	return c.Time
+}
+
+// clockFunc adapts a function to the Clock interface.
+type clockFunc func() time.Time
+
+func (f clockFunc) Now() time.Time {
+	return f()
+}
+
+// parseNow parses the value of --now or GOTT_NOW. Times without zone are in
+// loc.
+func parseNow(value string, loc *time.Location) (time.Time, error) {
+	for _, format := range nowFormats {
+		if t, err := time.ParseInLocation(format, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %s. use the format YYYY-MM-DD[ HH:MM[:SS]] or RFC 3339", value)
+}
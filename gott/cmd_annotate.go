@@ -7,20 +7,59 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var annotateCmd = &cobra.Command{
-	Use:   "annotate",
-	Short: "Set annotation for currently running tracking",
-	Run: func(cmd *cobra.Command, args []string) {
-		c, found := database.GetCurrent()
-		if !found {
-			fmt.Fprintln(os.Stderr, "ERROR: no tracking in process. unpointed annotionation is only valid for running trackings")
-			os.Exit(1)
-		}
-		lexInterval(args, c)
-		PrintRunningStatus()
-	},
-}
+func newAnnotateCmd(app *App) *cobra.Command {
+	var replace bool
+
+	cmd := &cobra.Command{
+		Use:   "annotate [@N] ANNOTATION",
+		Short: "Set annotation for currently running tracking or the given interval",
+		Long: `Change the annotation of the running interval or the given interval.
 
-func init() {
-	rootCmd.AddCommand(annotateCmd)
+Tags are added, -tag (after --) removes a tag, proj: and ref: without a value
+clear them. The text is kept unless new text is given. With --replace the
+interval is set to exactly the given annotation. Declared udas are set like
+estimate:2h and cleared like estimate:.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			c, found := app.Database.GetCurrent()
+			if len(args) > 0 && isHandle(args[0]) {
+				var err error
+				if c, err = resolveInterval(app.Database, args[0]); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+				found = true
+				args = args[1:]
+			}
+			if !found {
+				fmt.Fprintln(os.Stderr, "ERROR: no tracking in process. unpointed annotionation is only valid for running trackings")
+				os.Exit(1)
+			}
+			annotated := copyInterval(c)
+			if replace {
+				annotated.Tags = nil
+				annotated.Project = ""
+				annotated.Ref = ""
+				annotated.UDA = nil
+				if err := lexInterval(args, annotated, app.Config.UDAs); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+				annotated.Raw = formatRaw(annotated)
+			} else {
+				m, err := lexModification(args, app.Config.UDAs)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+				m.applyTo(annotated)
+			}
+			if err := app.Database.Apply(*annotated); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			app.PrintStatus(c)
+		},
+	}
+	cmd.Flags().BoolVar(&replace, "replace", false, "replace the annotation, project, tags and ref")
+	return cmd
 }
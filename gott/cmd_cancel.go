@@ -6,18 +6,16 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var cancelCmd = &cobra.Command{
-	Use:   "cancel",
-	Short: "Cancel currently running tracking",
-	Run: func(cmd *cobra.Command, args []string) {
-		if _, found := database.GetCurrent(); found {
-			database.Cancel()
-		} else {
-			fmt.Println("no tracking in progress")
-		}
-	},
-}
-
-func init() {
-	rootCmd.AddCommand(cancelCmd)
+func newCancelCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "cancel",
+		Short: "Cancel currently running tracking",
+		Run: func(cmd *cobra.Command, args []string) {
+			if _, found := app.Database.GetCurrent(); found {
+				app.Database.Cancel()
+			} else {
+				fmt.Fprintln(app.Out, "no tracking in progress")
+			}
+		},
+	}
 }
@@ -8,24 +8,31 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var continueCmd = &cobra.Command{
-	Use:   "continue",
-	Short: "Continue last running tracking",
-	Run: func(cmd *cobra.Command, args []string) {
-		if _, found := database.GetCurrent(); found {
-			fmt.Fprintln(os.Stderr, "ERROR: there is a tracking in progress. Nothing to continue.")
-			os.Exit(1)
-		}
-		if latest, err := database.Latest(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
-			os.Exit(1)
-		} else {
-			database.Start(NewInterval(strings.Split(latest.Raw, " ")))
-			PrintRunningStatus()
-		}
-	},
-}
-
-func init() {
-	rootCmd.AddCommand(continueCmd)
+func newContinueCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "continue [@N]",
+		Short: "Continue last running tracking or the given interval",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if _, found := app.Database.GetCurrent(); found {
+				fmt.Fprintln(os.Stderr, "ERROR: there is a tracking in progress. Nothing to continue.")
+				os.Exit(1)
+			}
+			latest, err := app.Database.Latest()
+			if len(args) == 1 {
+				latest, err = resolveInterval(app.Database, args[0])
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+			interval, err := app.newInterval(strings.Split(latest.Raw, " "))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			app.Database.Start(interval, app.clock().Now())
+			app.PrintRunningStatus()
+		},
+	}
 }
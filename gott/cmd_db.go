@@ -0,0 +1,124 @@
+package gott
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func newDbCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Maintain the database",
+	}
+	cmd.AddCommand(newDbMigrateCmd(app), newDbConvertCmd(app))
+	return cmd
+}
+
+func newDbConvertCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "convert SOURCE",
+		Short: "Copy all intervals of the database file SOURCE into the configured database",
+		Long: `Copy all intervals of the database file SOURCE into the configured database.
+
+The type of SOURCE is guessed by its file extension (.db, .sqlite and .sqlite3
+are sqlite databases, everything else is json). To migrate the json database
+into sqlite set databasename to e.g. db.sqlite and run:
+
+  gott db convert db.json`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			// the source is only read. it is neither saved nor migrated on disk
+			if _, err := os.Stat(args[0]); err != nil {
+				fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+				os.Exit(1)
+			}
+			if sameFile(args[0], app.Config.DatabaseName) {
+				fmt.Fprintf(os.Stderr, "ERROR: %s is the configured database\n", args[0])
+				os.Exit(1)
+			}
+			lock, err := lockDatabase(args[0], false, app.Config.LockTimeout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+				os.Exit(1)
+			}
+			defer lock.Unlock()
+			source, err := NewDatabase(args[0], "", app.calendar(), app.Config.UDAs)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+				os.Exit(1)
+			}
+			if err := source.Load(); err != nil {
+				fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+				os.Exit(1)
+			}
+			if c, ok := source.(io.Closer); ok {
+				defer c.Close()
+			}
+
+			intervals, _ := source.Filter([]string{KeyAll})
+			count := 0
+			for _, interval := range intervals {
+				if _, found := app.Database.Get(interval.ID); found {
+					continue
+				}
+				if err := app.Database.AppendPtr(interval); err != nil {
+					fmt.Fprintf(os.Stderr, "WARNING: skipped: %s\n", err.Error())
+					continue
+				}
+				count++
+			}
+			if current, found := source.GetCurrent(); found {
+				if err := app.Database.SetCurrent(current.ID); err != nil {
+					fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+					os.Exit(1)
+				}
+			}
+			fmt.Fprintf(app.Out, "copied %d of %d intervals from %s\n", count, len(intervals), args[0])
+		},
+	}
+}
+
+func newDbMigrateCmd(app *App) *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade the database file to the current schema version",
+		Long: `Upgrade the database file to the current schema version.
+
+Older database files are upgraded on every run of gott, but only written by
+commands which modify the database. Use --dry-run to see what would change.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var migrated []string
+			if m, ok := app.Journal.Database.(Migrator); ok {
+				migrated = m.Migrated()
+			}
+			if len(migrated) == 0 {
+				fmt.Fprintf(app.Out, "%s is up to date\n", app.Config.DatabaseName)
+				return
+			}
+			for _, m := range migrated {
+				fmt.Fprintln(app.Out, m)
+			}
+			if dryRun {
+				app.Discard()
+				fmt.Fprintf(app.Out, "dry run. %s was not changed\n", app.Config.DatabaseName)
+			} else {
+				fmt.Fprintf(app.Out, "migrated %s\n", app.Config.DatabaseName)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only report the migrations")
+	return cmd
+}
+
+// sameFile returns whether a and b name the same existing file.
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
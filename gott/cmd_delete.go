@@ -0,0 +1,38 @@
+package gott
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func newDeleteCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete @N...",
+		Short: "Delete the given intervals",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			// resolve all handles first, they change when intervals are deleted
+			var intervals []*Interval
+			for _, arg := range args {
+				i, err := resolveInterval(app.Database, arg)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+				intervals = append(intervals, i)
+			}
+			for n, i := range intervals {
+				if i.ID == app.Database.CurrentID() {
+					if err := app.Database.SetCurrent(""); err != nil {
+						fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+						os.Exit(1)
+					}
+				}
+				fmt.Fprintf(app.Out, "deleted %s %s\n", args[n], app.fmtInterval(i))
+				app.Database.RemoveById(i.ID)
+			}
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package gott
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cheynewallace/tabby"
+	"github.com/spf13/cobra"
+)
+
+func newDoctorCmd(app *App) *cobra.Command {
+	var fix bool
+	var overlap string
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the database for inconsistencies",
+		Long: `Check the database for inconsistencies and fix them with --fix.
+
+It reports duplicate ids, intervals ending before they begin, a status
+disagreeing with the end, a current interval which is missing or stopped,
+running intervals besides the current one and overlapping intervals.
+
+--fix drops duplicates, swaps begin and end, sets the status and points the
+current interval to the latest running one. Overlaps of stopped intervals are
+fixed with the --overlap strategy: trim ends the earlier interval where the
+later one begins, merge extends the earlier interval over the later one and
+removes the later one.`,
+		Annotations: map[string]string{annotationReadOnlyUnless: "fix"},
+		Args:        cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if !containsString(OverlapStrategies, overlap) {
+				fmt.Fprintf(os.Stderr, "ERROR: unknown overlap strategy %s. Choose one of %s\n", overlap, strings.Join(OverlapStrategies, ", "))
+				os.Exit(1)
+			}
+
+			problems, err := app.diagnose(overlap)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			if len(problems) == 0 {
+				fmt.Fprintln(app.Out, "no problems found")
+				return
+			}
+
+			t := tabby.NewCustom(tabwriter.NewWriter(app.Out, 0, 0, 2, ' ', 0))
+			t.AddHeader("PROBLEM", "INTERVALS", "DESCRIPTION", "FIX")
+			fixable := 0
+			for _, p := range problems {
+				fixText := p.Fix
+				if p.Fix == "" {
+					fixText = "-"
+				} else if fix {
+					if err := p.Apply(); err != nil {
+						fixText = "failed: " + err.Error()
+					}
+				}
+				if p.Fix != "" {
+					fixable++
+				}
+				t.AddLine(p.Kind, strings.Join(p.IDs, ", "), p.Description, fixText)
+			}
+			t.Print()
+
+			if !fix {
+				fmt.Fprintf(app.Out, "\n%d problems found, %d can be fixed with --fix\n", len(problems), fixable)
+				return
+			}
+			remaining, err := app.diagnose(overlap)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			fmt.Fprintf(app.Out, "\n%d problems found, %d remaining\n", len(problems), len(remaining))
+		},
+	}
+	cmd.Flags().BoolVar(&fix, "fix", false, "fix the problems")
+	cmd.Flags().StringVar(&overlap, "overlap", OverlapTrim, "strategy to fix overlaps: "+strings.Join(OverlapStrategies, " or "))
+	return cmd
+}
@@ -1,223 +1,199 @@
 package gott
 
 import (
-	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
-	"regexp"
+	"runtime"
 	"strings"
-	"text/tabwriter"
-	"time"
 
-	uuid "github.com/nu7hatch/gouuid"
 	"github.com/spf13/cobra"
 )
 
-func writeEditFile(f *os.File, intervals []*Interval, filterArgs []string) {
-
-	f.WriteString("# Edit below values to change tracking data\n")
-	f.WriteString("# - delete rows to delete \n")
-	f.WriteString("# - set time values 00:00 or leave empty to just set duration \n")
-	f.WriteString("# - leave duration empty or 0s if you set date \n")
-	f.WriteString("\n")
-
-	writer := tabwriter.NewWriter(f, 0, 0, 2, ' ', 0)
-	for _, i := range intervals {
-		line := fmt.Sprintf(
-			"(%s)\t(%s)\t(%s)\t(%s)\t(%s)\t(%s)\n",
-			i.ID,
-			i.Begin.Format(dateFormat),
-			i.Begin.Format(timeFormat),
-			i.End.Format(timeFormat),
-			i.Duration,
-			i.Raw,
-		)
-		writer.Write([]byte(line))
-	}
-
-	writer.Flush()
-
-	f.WriteString("\n\n# NEW ENTRIES HERE #############################################\n")
-	f.WriteString("# (ID [leave empty]) (DATE) (BEGIN) (END) (DURATION) (ANNOTATION)\n")
-	f.WriteString(fmt.Sprintf("\n# () (%s) () () () ()\n", time.Now().Format(dateFormat)))
-
-	f.WriteString("\n\n\n\n# meta #########################################################\n")
-	f.WriteString(fmt.Sprintf("# ;; filter == %s\n", strings.Join(filterArgs, " ")))
-
-}
-
-func runEditFile(f *os.File) {
-	excCmd := exec.Command("nvim", f.Name())
-	excCmd.Stdout = os.Stdout
-	excCmd.Stderr = os.Stderr
-	excCmd.Stdin = os.Stdin
-	excCmd.Run()
-}
-
-func parseEditLine(line string) (Interval, error) {
-
-	pattern := regexp.MustCompile(`\([\w\-\_0-9 :]*\)`)
-	result := pattern.FindAllString(line, -1)
-	var cleaned []string
-	for _, col := range result {
-		cleaned = append(cleaned, strings.Trim(stripBraces(col), " "))
-	}
-
-	var id, date, begin, end, duration, annotation string
-	unpackSlice(cleaned, &id, &date, &begin, &end, &duration, &annotation)
-	annotationSlice := strings.Split(annotation, " ")
-	interval := NewInterval(annotationSlice)
-
-	if date == "" {
-		return interval, fmt.Errorf("date is empty but should be filled with format YYYY-MM-DD")
-	}
-
-	tdate, tdateErr := time.Parse(dateFormat, date)
-	if tdateErr != nil {
-		return interval, fmt.Errorf("error parsing date '%s'. Error: %s", date, tdateErr.Error())
-	}
-
-	tbegin, terr := time.Parse(timeFormat, begin)
-	if begin != "" && terr != nil {
-		return interval, fmt.Errorf("error parsing begin time '%s': %s", begin, terr.Error())
+// editorCommand returns the editor configured in the config, $VISUAL or
+// $EDITOR, or else a default editor.
+func (a *App) editorCommand() string {
+	for _, editor := range []string{a.Config.Editor, os.Getenv("VISUAL"), os.Getenv("EDITOR")} {
+		if strings.TrimSpace(editor) != "" {
+			return editor
+		}
 	}
-
-	tend, tendErr := time.Parse(timeFormat, end)
-	if begin != "" && tendErr != nil {
-		return interval, fmt.Errorf("error parsing end time '%s': %s", end, tendErr.Error())
+	if runtime.GOOS == "windows" {
+		return "notepad"
 	}
+	return "vi"
+}
 
-	if tbegin.Format(timeFormat) != "00:00" && tend.Format(timeFormat) != "00:00" {
-		interval.Begin = tdate.Add(time.Duration(tbegin.Hour())*time.Hour + time.Duration(tbegin.Minute())*time.Minute)
-		interval.End = tdate.Add(time.Duration(tend.Hour())*time.Hour + time.Duration(tend.Minute())*time.Minute)
+// runEditor opens the file in the editor. The editor may have args like
+// "code --wait", it is run by the shell like git does.
+func runEditor(editor, name string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		args := strings.Fields(editor)
+		cmd = exec.Command(args[0], append(args[1:], name)...)
 	} else {
-		tdur, tdurErr := time.ParseDuration(duration)
-		if tdurErr != nil {
-			return interval, fmt.Errorf("error parsing duration: %s", tdurErr.Error())
-		}
-		interval.Begin = tdate
-		interval.End = tdate
-		interval.Duration = tdur
+		cmd = exec.Command("sh", "-c", editor+` "$@"`, editor, name)
 	}
-	interval.ID = id
-	return interval, nil
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %s", editor, err.Error())
+	}
+	return nil
 }
 
-func parseEditFile(f *os.File) ([]Interval, error) {
-	scanner := bufio.NewScanner(f)
-	scanner.Split(bufio.ScanLines)
-	var result []Interval
-	var line = 1
-	var pos int64 = 0
-	for scanner.Scan() {
-		t := scanner.Text()
-
-		pos += int64(len(t) + 1)
-		// ignore commented line
-		if strings.HasPrefix(t, "#") {
-			line += 1
-			continue
+// editUntilValid opens the file in the editor until it parses. Errors are
+// written into the file above their line. It returns false if the file is
+// unchanged or the user gives up.
+func (a *App) editUntilValid(name string) ([]Interval, bool, error) {
+	original, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, false, err
+	}
+	editor := a.editorCommand()
+	for {
+		if err := runEditor(editor, name); err != nil {
+			return nil, false, err
 		}
-		// ignore empty line
-		if len(strings.Trim(t, " ")) == 0 {
-			line += 1
-			continue
+		content, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, false, err
 		}
-		if i, err := parseEditLine(t); err != nil {
-			return nil, err
-		} else {
-			line += 1
-			result = append(result, i)
+		if bytes.Equal(annotateEditFile(content, nil), original) {
+			return nil, false, nil
 		}
-	}
-
-	return result, nil
-}
-
-var editCmd = &cobra.Command{
-	Use:   "edit",
-	Short: "Edit the intervals in the provided timespan",
-	Run: func(cmd *cobra.Command, args []string) {
 
-		if len(args) == 0 {
-			args = []string{KeyToday}
+		intervals, err := parseEditFile(bytes.NewReader(content), a.calendar(), a.Config.UDAs)
+		var lineErr *editLineError
+		if !errors.As(err, &lineErr) {
+			return intervals, err == nil, err
 		}
-		intervals, errFilter := database.Filter(args)
-		if errFilter != nil {
-			fmt.Fprintf(os.Stderr, "ERROR: invalid filter: %s", errFilter.Error())
-			os.Exit(1)
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+		if !a.confirm("edit the file again?") {
+			return nil, false, err
 		}
-
-		var beforeIDs []string
-		for _, i := range intervals {
-			beforeIDs = append(beforeIDs, i.ID)
-		}
-
-		f, _ := ioutil.TempFile(os.TempDir(), ".md")
-		defer f.Close()
-		defer os.Remove(f.Name())
-
-		writeEditFile(f, intervals, args)
-
-		beforeContent, _ := ioutil.ReadFile(f.Name())
-		runEditFile(f)
-		afterContent, _ := ioutil.ReadFile(f.Name())
-
-		if string(beforeContent) == string(afterContent) {
-			fmt.Println("file unchanged. nothing to do.")
-			return
+		if err := ioutil.WriteFile(name, annotateEditFile(content, lineErr), 0600); err != nil {
+			return nil, false, err
 		}
+	}
+}
 
-		// TODO: optimize:
-		// - test if content changed
-		// - test diff content and not walk through it
-		// - do not update every interval
-		// - better diff
-
-		f.Seek(0, 0)
-
-		editIntervals, err := parseEditFile(f)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "ERROR: error in parsing file: %s", err.Error())
-			os.Exit(1)
-		}
+func newEditCmd(app *App) *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "edit [FILTER]",
+		Short: "Edit the intervals in the provided timespan",
+		Long: `Edit the intervals matching FILTER (default :today) in your editor. The
+editor is the editor config, $VISUAL, $EDITOR or vi.
+
+If the file has errors, they are written into it and the editor opens again.
+The changes are listed and applied after confirmation, unless --yes is
+given. An added interval without end becomes the current one, only one
+interval can run.
+
+` + filterHelp,
+		Args: filterArgs(app),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				args = []string{KeyToday}
+			}
+			intervals, errFilter := app.Database.Filter(args)
+			if errFilter != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: invalid filter: %s\n", errFilter.Error())
+				os.Exit(1)
+			}
+			ids, err := handles(app.Database)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
 
-		var afterIDs []string
+			// .conf highlights the comments and quoted values in most editors
+			f, err := ioutil.TempFile("", "gott-edit-*.conf")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			defer os.Remove(f.Name())
+			writeEditFile(f, intervals, args, app.calendar())
+			if err := f.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
 
-		for _, intv := range editIntervals {
-			if intv.ID == "" {
-				id, _ := uuid.NewV4()
-				intv.ID = id.String()
-				database.Append(intv)
-			} else {
-				database.Apply(intv)
-				afterIDs = append(afterIDs, intv.ID)
+			edited, changed, err := app.editUntilValid(f.Name())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			if !changed {
+				fmt.Fprintln(app.Out, "file unchanged. nothing to do.")
+				return
 			}
-		}
 
-		if len(beforeIDs) != len(afterIDs) {
-			for _, beforeID := range beforeIDs {
-				if !containsString(afterIDs, beforeID) {
-					database.RemoveById(beforeID)
+			changes := app.diffEdit(intervals, edited)
+			if changes.Count() == 0 {
+				fmt.Fprintln(app.Out, "no intervals changed. nothing to do.")
+				return
+			}
+			// an added running interval becomes the current one. the file has
+			// at most one, but the current one may not be in it
+			var started *Interval
+			for n, i := range changes.Added {
+				if i.End.IsZero() {
+					started = &changes.Added[n]
 				}
 			}
-		}
-
-	},
-}
-
-func stripBraces(s string) string {
-	return s[1 : len(s)-1]
-}
+			inFile := map[string]bool{}
+			for _, i := range intervals {
+				inFile[i.ID] = true
+			}
+			if cur, found := app.Database.GetCurrent(); started != nil && found && cur.End.IsZero() && !inFile[cur.ID] {
+				fmt.Fprintf(os.Stderr, "ERROR: %s is running. stop it before adding a running interval\n", app.fmtInterval(cur))
+				os.Exit(1)
+			}
+			app.printEditChanges(intervals, changes, ids)
+			if !yes && !app.confirm(fmt.Sprintf("apply %d changes?", changes.Count())) {
+				app.Discard()
+				fmt.Fprintln(app.Out, "nothing was changed")
+				return
+			}
 
-func unpackSlice(s []string, vars ...*string) {
-	for i, str := range s {
-		*vars[i] = str
+			for _, i := range changes.Added {
+				if err := app.Database.Append(i); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+			}
+			for _, i := range changes.Changed {
+				if err := app.Database.Apply(i); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+			}
+			for _, i := range changes.Deleted {
+				app.Database.RemoveById(i.ID)
+			}
+			if cur, found := app.Database.GetCurrent(); !found || !cur.End.IsZero() {
+				if err := app.Database.SetCurrent(""); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+			}
+			if started != nil {
+				if err := app.Database.SetCurrent(started.ID); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+			}
+			fmt.Fprintf(app.Out, "applied %d changes\n", changes.Count())
+		},
 	}
-}
-
-func init() {
-	rootCmd.AddCommand(editCmd)
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "do not ask for confirmation")
+	return cmd
 }
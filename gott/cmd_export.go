@@ -0,0 +1,133 @@
+package gott
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	ExportFormatJSON  = "json"
+	ExportFormatJSONL = "jsonl"
+	ExportFormatCSV   = "csv"
+	ExportFormatTimew = "timew"
+)
+
+var ExportFormats = []string{ExportFormatJSON, ExportFormatJSONL, ExportFormatCSV, ExportFormatTimew}
+
+func newExportCmd(app *App) *cobra.Command {
+	var format string
+	var dataDir string
+	var columns []string
+	var mapping []string
+	var delimiter string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "export [FILTER]",
+		Short: "Export intervals for other tools",
+		Long: `Export the intervals matching FILTER (default :all).
+
+Formats:
+  json   an array of intervals with the fields
+           id          the id of the interval
+           start       the begin in RFC 3339
+           end         the end in RFC 3339, null while running and for
+                       intervals with only a duration
+           duration    ISO 8601 like PT1H30M, null while running
+           project, ref and annotation, "" for none
+           tags        [] for none
+           billable    whether the interval has the tag billable
+           uda         the udas by name, {} for none
+         Fields are only ever added. import json reads it again.
+  jsonl  one json interval per line
+  timew  the json of timew export. The project, ref and udas become tags
+         like proj:NAME, ref:ID and estimate:2h. With --data DIR the
+         intervals are written into DIR/data like timewarrior stores them,
+         so TIMEWARRIORDB=DIR timew report EXTENSION runs on gott data.
+         A DIR with month files is refused. --force replaces the files of
+         the exported months and the tags, other months stay.
+  csv    a header line and one line per interval with the --columns:
+         id, date, begin, end, duration (HH:MM), hours (decimal),
+         minutes, project, tags, ref, annotation and declared udas.
+         --map renames headers like --map date=Datum,hours=Stunden.
+
+` + filterHelp,
+		Annotations: readOnly,
+		ValidArgs:   Keys,
+		Args:        filterArgs(app),
+		Run: func(cmd *cobra.Command, args []string) {
+			if !containsString(ExportFormats, format) {
+				fmt.Fprintf(os.Stderr, "ERROR: unknown format %s. use one of %s\n", format, strings.Join(ExportFormats, ", "))
+				os.Exit(1)
+			}
+			if force && dataDir == "" {
+				fmt.Fprintln(os.Stderr, "ERROR: --force needs --data")
+				os.Exit(1)
+			}
+			if dataDir != "" && format != ExportFormatTimew {
+				fmt.Fprintf(os.Stderr, "ERROR: --data needs --format %s\n", ExportFormatTimew)
+				os.Exit(1)
+			}
+			var csvFmt csvFormat
+			if format == ExportFormatCSV {
+				var err error
+				if csvFmt, err = newCSVFormat(columns, mapping, delimiter, app.Config.UDAs); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+			}
+			if len(args) == 0 {
+				args = []string{KeyAll}
+			}
+			intervals, err := app.Database.Filter(args)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: invalid filter: %s\n", err.Error())
+				os.Exit(1)
+			}
+
+			switch format {
+			case ExportFormatJSON, ExportFormatJSONL:
+				if err := writeJSON(app.Out, intervals, app.calendar().Now().Location(), format == ExportFormatJSONL); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+				return
+			case ExportFormatCSV:
+				if err := writeCSV(app.Out, intervals, csvFmt, app.calendar()); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+				return
+			}
+			if dataDir != "" {
+				files, err := writeTimewData(dataDir, intervals, force)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+				fmt.Fprintf(app.Out, "exported %d intervals into %d files in %s\n", len(intervals), len(files), dataDir)
+				return
+			}
+
+			ids, err := handles(app.Database)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			if err := writeTimewJSON(app.Out, intervals, ids); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", ExportFormatJSON, "format of the export: "+strings.Join(ExportFormats, ", "))
+	cmd.Flags().StringVar(&dataDir, "data", "", "write a timewarrior data directory into `DIR` instead")
+	cmd.Flags().BoolVar(&force, "force", false, "replace the months in a --data DIR with timewarrior data")
+	cmd.Flags().StringSliceVar(&columns, "columns", DefaultCSVColumns, "columns of the csv export")
+	cmd.Flags().StringSliceVar(&mapping, "map", nil, "headers of csv columns like date=Datum")
+	cmd.Flags().StringVar(&delimiter, "delimiter", ",", "delimiter of the csv columns, one character or tab")
+	return cmd
+}
@@ -0,0 +1,82 @@
+package gott
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cheynewallace/tabby"
+	"github.com/spf13/cobra"
+)
+
+func newHistoryCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:         "history [N]",
+		Short:       "List the last N (default all) modifications",
+		Annotations: readOnly,
+		Args:        cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			entries, err := app.Journal.Entries()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			if len(args) == 1 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n < 1 {
+					fmt.Fprintf(os.Stderr, "ERROR: invalid number %s\n", args[0])
+					os.Exit(1)
+				}
+				if n < len(entries) {
+					entries = entries[len(entries)-n:]
+				}
+			}
+
+			undone := map[int]int{}
+			for _, e := range entries {
+				for _, seq := range e.Undoes {
+					undone[seq] = e.Seq
+				}
+			}
+
+			t := tabby.NewCustom(tabwriter.NewWriter(app.Out, 0, 0, 2, ' ', 0))
+			t.AddHeader("#", "TIME", "CHANGES", "COMMAND", "")
+			for _, e := range entries {
+				note := ""
+				if seq, found := undone[e.Seq]; found {
+					note = fmt.Sprintf("undone by #%d", seq)
+				}
+				t.AddLine(e.Seq, app.local(e.Time).Format(datetimeFormat), fmtChanges(e.Changes), e.Command, note)
+			}
+			t.Print()
+		},
+	}
+}
+
+// fmtChanges counts the created, modified and removed intervals.
+func fmtChanges(changes []JournalChange) string {
+	var created, modified, removed int
+	for _, c := range changes {
+		switch {
+		case c.Before == nil:
+			created++
+		case c.After == nil:
+			removed++
+		default:
+			modified++
+		}
+	}
+	var parts []string
+	if created > 0 {
+		parts = append(parts, fmt.Sprintf("+%d", created))
+	}
+	if modified > 0 {
+		parts = append(parts, fmt.Sprintf("~%d", modified))
+	}
+	if removed > 0 {
+		parts = append(parts, fmt.Sprintf("-%d", removed))
+	}
+	return strings.Join(parts, " ")
+}
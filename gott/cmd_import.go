@@ -0,0 +1,389 @@
+package gott
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+func newImportCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import intervals of other time trackers",
+	}
+	cmd.AddCommand(
+		newImportCSVCmd(app),
+		newImportJSONCmd(app),
+		newImportTimewarriorCmd(app),
+	)
+	return cmd
+}
+
+// printImported reports the number of imported and updated intervals. A dry
+// run discards the import.
+func (a *App) printImported(imported, updated, skipped int, dryRun bool) {
+	if dryRun {
+		a.Discard()
+		if updated > 0 {
+			fmt.Fprintf(a.Out, "dry run. would import %d intervals, update %d, skip %d imported before\n", imported, updated, skipped)
+			return
+		}
+		fmt.Fprintf(a.Out, "dry run. would import %d intervals, skip %d imported before\n", imported, skipped)
+		return
+	}
+	if updated > 0 {
+		fmt.Fprintf(a.Out, "imported %d intervals, updated %d, skipped %d imported before\n", imported, updated, skipped)
+		return
+	}
+	fmt.Fprintf(a.Out, "imported %d intervals, skipped %d imported before\n", imported, skipped)
+}
+
+func newImportJSONCmd(app *App) *cobra.Command {
+	var dryRun bool
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "json FILE",
+		Short: "Import intervals exported as json or jsonl",
+		Long: `Import intervals exported with export --format json or jsonl, - reads
+stdin.
+
+Intervals are merged by id: the fields of the file replace the ones of the
+interval with the id, missing fields are kept. Intervals with an unknown or
+without id are added and need a start. Importing an unchanged export changes
+nothing, so scripts can export, change and import intervals.
+
+The intervals are checked like track does, nothing is imported if one is
+invalid or overlaps another, unless --force is given. --dry-run lists the
+changes without saving them.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			r := app.In
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+				defer f.Close()
+				r = f
+			}
+			records, err := readJSON(r)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s: %s\n", args[0], err.Error())
+				os.Exit(1)
+			}
+			ids, err := handles(app.Database)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+
+			var changes editChanges
+			var before []*Interval
+			unchanged := 0
+			seen := map[string]bool{}
+			now := app.clock().Now()
+			for _, record := range records {
+				old, found := app.Database.Get(record.Interval.ID)
+				found = found && record.Interval.ID != ""
+				interval := NewInterval(nil)
+				if found {
+					before = append(before, copyInterval(old))
+					interval = *copyInterval(old)
+				} else if record.Interval.ID != "" {
+					interval.ID = record.Interval.ID
+				}
+				if seen[interval.ID] {
+					fmt.Fprintf(os.Stderr, "ERROR: %s: interval %d: the id %s is used twice\n", args[0], record.N, interval.ID)
+					os.Exit(1)
+				}
+				seen[interval.ID] = true
+				if err := record.applyTo(&interval, !found, app.Config.UDAs); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s: interval %d: %s\n", args[0], record.N, err.Error())
+					os.Exit(1)
+				}
+				if found && len(app.describeChanges(old, &interval)) == 0 {
+					unchanged++
+					continue
+				}
+
+				overlapping, err := findOverlaps(app.Database, &interval, now)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+				if len(overlapping) > 0 {
+					level := "ERROR"
+					if force {
+						level = "WARNING"
+					}
+					app.printOverlaps(level, &interval, overlapping)
+					if !force {
+						fmt.Fprintf(os.Stderr, "%s: interval %d: use --force to import it anyway\n", args[0], record.N)
+						os.Exit(1)
+					}
+				}
+
+				if found {
+					changes.Changed = append(changes.Changed, interval)
+					err = app.Database.Apply(interval)
+				} else {
+					changes.Added = append(changes.Added, interval)
+					err = app.Database.Append(interval)
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s: interval %d: %s\n", args[0], record.N, err.Error())
+					os.Exit(1)
+				}
+			}
+
+			// like edit, a stopped current interval is no longer current and a
+			// running one becomes current if there is none
+			cur, found := app.Database.GetCurrent()
+			if found && !cur.End.IsZero() {
+				found = false
+				err = app.Database.SetCurrent("")
+			}
+			for _, i := range changes.Added {
+				if !found && i.End.IsZero() && err == nil {
+					found = true
+					err = app.Database.SetCurrent(i.ID)
+				}
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+
+			if dryRun {
+				app.printEditChanges(before, changes, ids)
+				app.Discard()
+				fmt.Fprintf(app.Out, "dry run. would add %d intervals, change %d, leave %d unchanged\n", len(changes.Added), len(changes.Changed), unchanged)
+				return
+			}
+			fmt.Fprintf(app.Out, "added %d intervals, changed %d, left %d unchanged\n", len(changes.Added), len(changes.Changed), unchanged)
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only list the changes")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "import even if intervals overlap others")
+	return cmd
+}
+
+func newImportCSVCmd(app *App) *cobra.Command {
+	var mapping []string
+	var delimiter string
+	var dryRun bool
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "csv FILE",
+		Short: "Import intervals from a csv file",
+		Long: `Import intervals from a csv file, - reads stdin.
+
+The first line has the headers. The columns are found by the headers id,
+date, begin, end, duration, hours, minutes, project, tags, ref, annotation
+and the names of declared udas, or by the headers given with --map like
+--map date=Datum,hours=Stunden. A date and a begin and end or a duration in
+HH:MM, like 1h30m, in decimal hours or in minutes are required. Decimal
+commas are accepted.
+
+The intervals are checked like track does, nothing is imported if one is
+invalid or overlaps another, unless --force is given. Lines imported before
+are skipped, so the import can be run again.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := newCSVFormat(nil, mapping, delimiter, app.Config.UDAs)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			r := app.In
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+				defer f.Close()
+				r = f
+			}
+			records, err := readCSV(r, format)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s: %s\n", args[0], err.Error())
+				os.Exit(1)
+			}
+
+			imported, skipped := 0, 0
+			now := app.clock().Now()
+			for _, record := range records {
+				interval, err := record.toInterval(app.calendar(), app.Config.UDAs)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s:%d: %s\n", args[0], record.Line, err.Error())
+					os.Exit(1)
+				}
+				if _, found := app.Database.Get(interval.ID); found {
+					skipped++
+					continue
+				}
+				overlapping, err := findOverlaps(app.Database, &interval, now)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+				if len(overlapping) > 0 {
+					level := "ERROR"
+					if force {
+						level = "WARNING"
+					}
+					app.printOverlaps(level, &interval, overlapping)
+					if !force {
+						fmt.Fprintf(os.Stderr, "%s:%d: use --force to import it anyway\n", args[0], record.Line)
+						os.Exit(1)
+					}
+				}
+				if err := app.Database.Append(interval); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s:%d: %s\n", args[0], record.Line, err.Error())
+					os.Exit(1)
+				}
+				imported++
+			}
+			app.printImported(imported, 0, skipped, dryRun)
+		},
+	}
+	cmd.Flags().StringSliceVar(&mapping, "map", nil, "headers of the columns like date=Datum")
+	cmd.Flags().StringVar(&delimiter, "delimiter", ",", "delimiter of the columns, one character or tab")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only report what would be imported")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "import even if intervals overlap others")
+	return cmd
+}
+
+func newImportTimewarriorCmd(app *App) *cobra.Command {
+	var dryRun, force bool
+
+	cmd := &cobra.Command{
+		Use:   "timewarrior [PATH]",
+		Short: "Import the intervals of timewarrior",
+		Long: `Import the intervals of timewarrior.
+
+PATH is the timewarrior directory, its data directory or a single data file
+like 2022-01.data. It defaults to $TIMEWARRIORDB or ~/.timewarrior.
+
+The tags proj:NAME, project:NAME, ref:ID and declared udas like estimate:2h
+set the project, ref and udas, +tag and all other tags become tags. Intervals
+imported before are skipped, so the import can be run again. Intervals with
+the start and end of an existing interval, like those of gott export --format
+timew, are skipped as well. Imported intervals which were running get the end
+they have in timewarrior now. Intervals overlapping others are refused unless
+--force is given.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			path := defaultTimewPath()
+			if len(args) == 1 {
+				path = args[0]
+			}
+			files, err := timewDataFiles(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+
+			imported, updated, skipped := 0, 0, 0
+			running := ""
+			now := app.clock().Now()
+			for _, file := range files {
+				f, err := os.Open(file)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+				intervals, err := readTimewFile(f)
+				f.Close()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s: %s\n", file, err.Error())
+					os.Exit(1)
+				}
+
+				fileImported, fileUpdated, fileSkipped := 0, 0, 0
+				for _, t := range intervals {
+					i := t.toInterval(app.Config.UDAs)
+					if existing, found := app.Database.Get(i.ID); found {
+						if !existing.End.IsZero() || i.End.IsZero() {
+							fileSkipped++
+							continue
+						}
+						// it was running on the last import and has ended since
+						stopped := *existing
+						stopped.Stop(i.End)
+						if err := app.Database.Apply(stopped); err != nil {
+							fmt.Fprintf(os.Stderr, "ERROR: %s: %s\n", file, err.Error())
+							os.Exit(1)
+						}
+						if app.Database.CurrentID() == stopped.ID {
+							if err := app.Database.SetCurrent(""); err != nil {
+								fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+								os.Exit(1)
+							}
+						}
+						fileUpdated++
+						continue
+					}
+					if _, found, err := findTimewInterval(app.Database, t, now); err != nil {
+						fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+						os.Exit(1)
+					} else if found {
+						fileSkipped++
+						continue
+					}
+
+					overlapping, err := findOverlaps(app.Database, &i, now)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+						os.Exit(1)
+					}
+					if len(overlapping) > 0 {
+						level := "ERROR"
+						if force {
+							level = "WARNING"
+						}
+						app.printOverlaps(level, &i, overlapping)
+						if !force {
+							fmt.Fprintf(os.Stderr, "%s: use --force to import it anyway\n", file)
+							os.Exit(1)
+						}
+					}
+
+					if err := app.Database.Append(i); err != nil {
+						fmt.Fprintf(os.Stderr, "ERROR: %s: %s\n", file, err.Error())
+						os.Exit(1)
+					}
+					if i.End.IsZero() {
+						running = i.ID
+					}
+					fileImported++
+				}
+				fmt.Fprintf(app.Out, "%s: imported %d, updated %d, skipped %d\n", filepath.Base(file), fileImported, fileUpdated, fileSkipped)
+				imported += fileImported
+				updated += fileUpdated
+				skipped += fileSkipped
+			}
+
+			if running != "" {
+				if app.Database.CurrentID() == "" {
+					if err := app.Database.SetCurrent(running); err != nil {
+						fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+						os.Exit(1)
+					}
+				} else {
+					fmt.Fprintln(os.Stderr, "WARNING: the running timewarrior interval is imported, but gott tracks another one. run gott doctor to repair it")
+				}
+			}
+			app.printImported(imported, updated, skipped, dryRun)
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only report what would be imported")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "import even if intervals overlap others")
+	return cmd
+}
@@ -0,0 +1,172 @@
+package gott
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newModifyCmd(app *App) *cobra.Command {
+	var begin, end string
+	var dryRun, yes, force bool
+
+	cmd := &cobra.Command{
+		Use:   "modify @N...|FILTER MODIFICATION",
+		Short: "Change the given intervals",
+		Long: `Change the intervals with the given handles or matching FILTER.
+
+FILTER is one argument, quote it if it consists of several terms. The
+modification is made of
+
+  proj:NAME     set the project, proj: clears it
+  +tag          add the tag
+  -tag          remove the tag (after --)
+  ref:ID        set the ref, ref: clears it
+  estimate:2h   set a declared uda, estimate: clears it
+  text          replace the annotation
+
+--begin and --end take HH:MM on the working day of each interval or
+YYYY-MM-DD HH:MM. Changing more than bulk (default 3) intervals asks for
+confirmation, unless --yes is given.
+
+Example: gott modify ":week proj:gott" -- proj:gott.docs -draft`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			targets, args, err := resolveTargets(app, args)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			m, err := lexModification(args, app.Config.UDAs)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			if m.Empty() && begin == "" && end == "" {
+				fmt.Fprintln(os.Stderr, "ERROR: nothing to modify")
+				os.Exit(1)
+			}
+			if len(targets) == 0 {
+				fmt.Fprintln(app.Out, "no intervals match")
+				return
+			}
+
+			ids, err := handles(app.Database)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			cal := app.calendar()
+			now := app.clock().Now()
+			var modified, moved []*Interval
+			for _, i := range targets {
+				im := m
+				day := cal.Day(app.local(i.Begin))
+				if begin != "" {
+					if im.Begin, err = parseTimeOn(begin, day, cal); err != nil {
+						fmt.Fprintln(os.Stderr, err.Error())
+						os.Exit(1)
+					}
+				}
+				if end != "" {
+					if im.End, err = parseTimeOn(end, day, cal); err != nil {
+						fmt.Fprintln(os.Stderr, err.Error())
+						os.Exit(1)
+					}
+				}
+				after := copyInterval(i)
+				im.applyTo(after)
+				if err := after.Validate(); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+
+				fmt.Fprintf(app.Out, "%s %s\n", ids[i.ID], app.fmtInterval(i))
+				changes := app.describeChanges(i, after)
+				if len(changes) == 0 {
+					fmt.Fprintln(app.Out, "  unchanged")
+					continue
+				}
+				for _, change := range changes {
+					fmt.Fprintf(app.Out, "  %s\n", change)
+				}
+				if !after.Begin.Equal(i.Begin) || !after.End.Equal(i.End) {
+					moved = append(moved, after)
+				}
+				modified = append(modified, after)
+			}
+
+			// the moved intervals are checked against each other too
+			if len(moved) > 0 && !force {
+				overlapping, err := findBatchOverlaps(app.Database, modified, now)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+				for _, i := range moved {
+					if len(overlapping[i.ID]) > 0 {
+						app.printOverlaps("ERROR", i, overlapping[i.ID])
+						fmt.Fprintln(os.Stderr, "use --force to modify it anyway")
+						os.Exit(1)
+					}
+				}
+			}
+
+			if dryRun {
+				app.Discard()
+				fmt.Fprintln(app.Out, "dry run. nothing was changed")
+				return
+			}
+			if len(modified) > app.Config.Bulk && !yes {
+				if !app.confirm(fmt.Sprintf("modify %d intervals?", len(modified))) {
+					app.Discard()
+					fmt.Fprintln(app.Out, "nothing was changed")
+					return
+				}
+			}
+			for _, i := range modified {
+				if err := app.Database.Apply(*i); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+					os.Exit(1)
+				}
+				if i.ID == app.Database.CurrentID() && !i.End.IsZero() {
+					if err := app.Database.SetCurrent(""); err != nil {
+						fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+						os.Exit(1)
+					}
+				}
+			}
+			fmt.Fprintf(app.Out, "modified %d intervals\n", len(modified))
+		},
+	}
+	cmd.Flags().StringVar(&begin, "begin", "", "set the begin to HH:MM or YYYY-MM-DD HH:MM")
+	cmd.Flags().StringVar(&end, "end", "", "set the end to HH:MM or YYYY-MM-DD HH:MM")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only show the changes")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "do not ask for confirmation")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "modify even if the intervals overlap others")
+	return cmd
+}
+
+// resolveTargets returns the intervals addressed by the leading handles of
+// args or else by the filter in the first arg, and the remaining args.
+func resolveTargets(app *App, args []string) ([]*Interval, []string, error) {
+	var targets []*Interval
+	for len(args) > 0 && isHandle(args[0]) {
+		i, err := resolveInterval(app.Database, args[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		targets = append(targets, i)
+		args = args[1:]
+	}
+	if len(targets) > 0 {
+		return targets, args, nil
+	}
+	targets, err := app.Database.Filter(strings.Fields(args[0]))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid filter: %s", err.Error())
+	}
+	return targets, args[1:], nil
+}
@@ -1,10 +1,96 @@
 package gott
 
-import "github.com/spf13/cobra"
+import (
+	"fmt"
+	"os"
 
-var rootCmd = &cobra.Command{
-	Use: "gott",
-	Run: func(cmd *cobra.Command, args []string) {
-		PrintRunningStatus()
-	},
+	"github.com/spf13/cobra"
+)
+
+const filterHelp = `FILTER is an expression of the following terms. Terms next to each other
+must all match, use "or", "not" and parentheses to combine them differently.
+
+  :today :yesterday                     a working day
+  :week :month :quarter :year           the period until today
+  :lastweek :lastmonth ...              the whole previous period
+  monday, 3d ago, -2w, sow, eom         days relative to today
+  2022-01-14 2022-W07                   a working day or ISO week
+  2022-01-01..2022-01-31, -1w..         a range of working days
+  :all                                  all intervals
+  proj:gott project:gott                project and its subprojects
+  +tag                                  with tag
+  -tag                                  without tag (after --)
+  ref:ID-*                              ref, * matches any text
+  estimate:2h estimate:1h..4h estimate:   declared udas, a range or none
+  text                                  annotation containing text
+
+Example: gott summary -- :week "(proj:gott or +docs)" not -billable`
+
+// filterArgs validates the filter expression in args.
+func filterArgs(app *App) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if _, err := parseFilter(args, app.calendar(), app.Config.UDAs); err != nil {
+			return fmt.Errorf("invalid filter: %s", err.Error())
+		}
+		return nil
+	}
+}
+
+// NewRootCmd creates the gott command with all subcommands working on app.
+// The database of app is opened before a subcommand runs.
+func NewRootCmd(app *App) *cobra.Command {
+	var now string
+
+	rootCmd := &cobra.Command{
+		Use:         "gott",
+		Annotations: readOnly,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if now == "" {
+				now = os.Getenv(EnvNow)
+			}
+			if now != "" {
+				t, err := parseNow(now, app.clock().Now().Location())
+				if err != nil {
+					return err
+				}
+				app.Clock = FixedClock{Time: t}
+			}
+			readOnly := cmd.Annotations[annotationReadOnly] == "true"
+			if flag := cmd.Annotations[annotationReadOnlyUnless]; flag != "" {
+				set, _ := cmd.Flags().GetBool(flag)
+				readOnly = !set
+			}
+			if err := app.Open(readOnly); err != nil {
+				// not a usage error
+				cmd.SilenceUsage = true
+				return err
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			app.PrintRunningStatus()
+		},
+	}
+
+	rootCmd.PersistentFlags().StringVar(&now, "now", "", "run as of the given time (YYYY-MM-DD[ HH:MM[:SS]]). defaults to $"+EnvNow)
+
+	rootCmd.AddCommand(
+		newAnnotateCmd(app),
+		newCancelCmd(app),
+		newContinueCmd(app),
+		newDbCmd(app),
+		newDeleteCmd(app),
+		newDoctorCmd(app),
+		newEditCmd(app),
+		newExportCmd(app),
+		newHistoryCmd(app),
+		newImportCmd(app),
+		newModifyCmd(app),
+		newStartCmd(app),
+		newStopCmd(app),
+		newSummaryCmd(app),
+		newTrackCmd(app),
+		newUndoCmd(app),
+	)
+	return rootCmd
 }
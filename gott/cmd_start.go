@@ -1,17 +1,46 @@
 package gott
 
-import "github.com/spf13/cobra"
+import (
+	"fmt"
+	"os"
 
-var startCmd = &cobra.Command{
-	Use:   "start",
-	Short: "Start tracking",
-	Run: func(cmd *cobra.Command, args []string) {
-		interval := NewInterval(args)
-		database.Start(interval)
-		PrintRunningStatus()
-	},
-}
+	"github.com/spf13/cobra"
+)
+
+func newStartCmd(app *App) *cobra.Command {
+	var at string
+	var force bool
 
-func init() {
-	rootCmd.AddCommand(startCmd)
+	cmd := &cobra.Command{
+		Use:   "start [DURATION ago] [ANNOTATION]",
+		Short: "Start tracking",
+		Run: func(cmd *cobra.Command, args []string) {
+			begin, args, err := lexAt(at, args, app.calendar())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			interval, err := app.newInterval(args)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			overlapping, err := checkStart(app.Database, interval, begin, app.clock().Now())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			if len(overlapping) > 0 && !force {
+				interval.Begin = begin
+				app.printOverlaps("ERROR", &interval, overlapping)
+				fmt.Fprintln(os.Stderr, "use --force to start it anyway")
+				os.Exit(1)
+			}
+			app.Database.Start(interval, begin)
+			app.PrintRunningStatus()
+		},
+	}
+	cmd.Flags().StringVar(&at, "at", "", "start at HH:MM or YYYY-MM-DD HH:MM instead of now")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "start even if the interval overlaps others")
+	return cmd
 }
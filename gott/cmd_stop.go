@@ -2,24 +2,51 @@ package gott
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 )
 
-var stopCmd = &cobra.Command{
-	Use:   "stop",
-	Short: "Stop currently running tracking",
-	Run: func(cmd *cobra.Command, args []string) {
-		current, found := database.GetCurrent()
-		database.Stop()
-		if !found {
-			fmt.Println("<< no tracking in progress >>")
-		} else {
-			PrintStatus(current)
-		}
-	},
-}
+func newStopCmd(app *App) *cobra.Command {
+	var at string
+	var force bool
 
-func init() {
-	rootCmd.AddCommand(stopCmd)
+	cmd := &cobra.Command{
+		Use:   "stop [DURATION ago]",
+		Short: "Stop currently running tracking",
+		Args:  cobra.MaximumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			end, args, err := lexAt(at, args, app.calendar())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			if len(args) > 0 {
+				fmt.Fprintln(os.Stderr, "ERROR: must have the format DURATION ago")
+				os.Exit(1)
+			}
+			current, found := app.Database.GetCurrent()
+			if !found {
+				fmt.Fprintln(app.Out, "<< no tracking in progress >>")
+				return
+			}
+			overlapping, err := checkStop(app.Database, end, app.clock().Now())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			if len(overlapping) > 0 && !force {
+				stopped := copyInterval(current)
+				stopped.Stop(end)
+				app.printOverlaps("ERROR", stopped, overlapping)
+				fmt.Fprintln(os.Stderr, "use --force to stop it anyway")
+				os.Exit(1)
+			}
+			app.Database.Stop(end)
+			app.PrintStatus(current)
+		},
+	}
+	cmd.Flags().StringVar(&at, "at", "", "stop at HH:MM or YYYY-MM-DD HH:MM instead of now")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "stop even if the interval overlaps others")
+	return cmd
 }
@@ -11,100 +11,114 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var summaryCmd = &cobra.Command{
-	Use:       "summary",
-	Short:     "Print tracking summary for a given timespan",
-	ValidArgs: Keys,
-	Args: func(cmd *cobra.Command, args []string) error {
-		if len(args) == 0 {
-			return nil
-		}
-		if len(args) > 1 {
-			return fmt.Errorf(
-				"args should only be one. Choose one of the keys %s, %s, %s, %s or %s or provide in the format YYYY-MM-DD",
-				KeyToday, KeyYesterday, KeyWeek, KeyMonth, KeyAll,
-			)
-		}
-		for _, key := range Keys {
-			// key found. so it's valid
-			if containsString(args, key) {
-				return nil
-			}
-		}
-		if _, err := time.Parse(dateFormat, args[0]); err != nil {
-			return fmt.Errorf(
-				"invalid date format. Choose one of the keys %s, %s, %s, %s or %s or provide in the format YYYY-MM-DD",
-				KeyToday, KeyYesterday, KeyMonth, KeyWeek, KeyAll,
-			)
-
-		}
-		return nil
-	},
-	Run: func(cmd *cobra.Command, args []string) {
-
-		writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
-		t := tabby.NewCustom(writer)
+func newSummaryCmd(app *App) *cobra.Command {
+	var udaColumns []string
 
-		t.AddHeader("CWEEK", "DAY", "BEGIN", "END", "DURATION", "PROJECT", "TAG", "ANNOTATION")
+	cmd := &cobra.Command{
+		Use:         "summary [FILTER]",
+		Short:       "Print tracking summary for a given timespan",
+		Long:        "Print tracking summary for the intervals matching FILTER (default :today).\n\n" + filterHelp,
+		Annotations: readOnly,
+		ValidArgs:   Keys,
+		Args:        filterArgs(app),
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, name := range udaColumns {
+				if _, found := app.Config.UDAs.Get(name); !found {
+					fmt.Fprintf(os.Stderr, "ERROR: uda %s is not declared\n", name)
+					os.Exit(1)
+				}
+			}
 
-		weekGroup := 0
-		weekText := ""
-		dayGroup := ""
-		dayText := ""
-		var dayDurationSum time.Duration
-		var weekDurationSum time.Duration
-		if len(args) == 0 {
-			args = []string{KeyToday}
-		}
-		intervals, filterError := database.Filter(args)
-		if filterError != nil {
-			fmt.Fprintf(os.Stderr, "ERROR: invalid filter: %s", filterError.Error())
-			os.Exit(1)
-		}
-		for _, interval := range intervals {
+			writer := tabwriter.NewWriter(app.Out, 0, 0, 2, ' ', 0)
+			t := tabby.NewCustom(writer)
 
-			endText := "tracking..."
-			if !interval.End.IsZero() {
-				endText = interval.End.Format(timeFormat)
+			header := []interface{}{"CWEEK", "DAY", "ID", "BEGIN", "END", "DURATION", "PROJECT", "TAG"}
+			for _, name := range udaColumns {
+				header = append(header, strings.ToUpper(name))
 			}
+			t.AddHeader(append(header, "ANNOTATION")...)
 
-			if d := interval.Begin.Format(dateFormatShort); d != dayGroup {
-				dayGroup = d
-				dayText = d
-				DaySumLine(t, weekDurationSum)
-			} else {
-				dayText = ""
+			weekGroup := 0
+			weekText := ""
+			dayGroup := ""
+			dayText := ""
+			var dayDurationSum time.Duration
+			var weekDurationSum time.Duration
+			if len(args) == 0 {
+				args = []string{KeyToday}
 			}
-
-			if _, w := interval.Begin.ISOWeek(); w != weekGroup {
-				weekGroup = w
-				weekText = fmt.Sprint(w)
-				WeekSumLine(t, dayDurationSum)
-			} else {
-				weekText = ""
+			cal := app.calendar()
+			now := cal.Now()
+			intervals, filterError := app.Database.Filter(args)
+			if filterError != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: invalid filter: %s\n", filterError.Error())
+				os.Exit(1)
+			}
+			ids, err := handles(app.Database)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
 			}
+			for _, interval := range intervals {
 
-			weekDurationSum += interval.GetDuration()
-			dayDurationSum += interval.GetDuration()
+				begin := app.local(interval.Begin)
+				endText := "tracking..."
+				if !interval.End.IsZero() {
+					endText = app.local(interval.End).Format(timeFormat)
+				}
 
-			t.AddLine(
-				weekText,
-				dayText,
-				interval.Begin.Format(timeFormat),
-				endText,
-				fmtDuration(interval.GetDuration()),
-				interval.Project,
-				strings.Join(interval.Tags, ", "),
-				interval.Annotation,
-			)
-		}
-		DaySumLine(t, dayDurationSum)
-		WeekSumLine(t, dayDurationSum)
+				// group by working day, not by calendar day
+				day := cal.Day(begin)
 
-		t.Print()
-	},
-}
+				if _, w := day.ISOWeek(); w != weekGroup {
+					DaySumLine(t, dayDurationSum)
+					WeekSumLine(t, weekDurationSum)
+					dayDurationSum = 0
+					weekDurationSum = 0
+					dayGroup = ""
+					weekGroup = w
+					weekText = fmt.Sprint(w)
+				} else {
+					weekText = ""
+				}
+
+				if d := day.Format(dateFormatShort); d != dayGroup {
+					DaySumLine(t, dayDurationSum)
+					dayDurationSum = 0
+					dayGroup = d
+					dayText = d
+				} else {
+					dayText = ""
+				}
+
+				weekDurationSum += interval.GetDuration(now)
+				dayDurationSum += interval.GetDuration(now)
+
+				line := []interface{}{
+					weekText,
+					dayText,
+					ids[interval.ID],
+					begin.Format(timeFormat),
+					endText,
+					fmtDuration(interval.GetDuration(now)),
+					interval.Project,
+					strings.Join(interval.Tags, ", "),
+				}
+				for _, name := range udaColumns {
+					value := ""
+					if v, found := interval.UDA[name]; found {
+						value = fmtUDAValue(v)
+					}
+					line = append(line, value)
+				}
+				t.AddLine(append(line, interval.Annotation)...)
+			}
+			DaySumLine(t, dayDurationSum)
+			WeekSumLine(t, weekDurationSum)
 
-func init() {
-	rootCmd.AddCommand(summaryCmd)
+			t.Print()
+		},
+	}
+	cmd.Flags().StringSliceVar(&udaColumns, "uda", nil, "show the declared udas as columns, e.g. --uda estimate,client")
+	return cmd
 }
@@ -7,26 +7,47 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var trackCmd = &cobra.Command{
-	Use:   "track",
-	Short: "Add interval for a date/keyword",
-	Args: func(cmd *cobra.Command, args []string) error {
-		if len(args) > 1 {
-			return nil
-		}
-		return fmt.Errorf("must have the format DATE DURATION -- ANNOTATION")
-	},
-	Run: func(cmd *cobra.Command, args []string) {
-		interval := NewInterval(args[2:])
-		if err := lexTrack(args[0:2], &interval); err != nil {
-			fmt.Fprintln(os.Stderr, err.Error())
-			os.Exit(1)
-		} else {
-			database.Append(interval)
-		}
-	},
-}
+func newTrackCmd(app *App) *cobra.Command {
+	var force bool
 
-func init() {
-	rootCmd.AddCommand(trackCmd)
+	cmd := &cobra.Command{
+		Use:   "track DATE DURATION|BEGIN-END|BEGIN for DURATION [ANNOTATION]",
+		Short: "Add interval for a date/keyword",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return nil
+			}
+			return fmt.Errorf("must have the format DATE DURATION -- ANNOTATION")
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			interval := NewInterval(nil)
+			if err := lexTrack(args, &interval, app.calendar(), app.Config.UDAs); err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			app.Config.UDAs.setDefaults(&interval)
+			overlapping, err := findOverlaps(app.Database, &interval, app.clock().Now())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			if len(overlapping) > 0 {
+				level := "ERROR"
+				if force {
+					level = "WARNING"
+				}
+				app.printOverlaps(level, &interval, overlapping)
+				if !force {
+					fmt.Fprintln(os.Stderr, "use --force to track it anyway")
+					os.Exit(1)
+				}
+			}
+			if err := app.Database.Append(interval); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "track even if the interval overlaps others")
+	return cmd
 }
@@ -0,0 +1,39 @@
+package gott
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func newUndoCmd(app *App) *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "undo [N]",
+		Short: "Undo the last N (default 1) modifications",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			n := 1
+			if len(args) == 1 {
+				var err error
+				if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+					fmt.Fprintf(os.Stderr, "ERROR: invalid number %s\n", args[0])
+					os.Exit(1)
+				}
+			}
+			undone, err := app.Journal.Undo(n, force)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+				os.Exit(1)
+			}
+			for _, entry := range undone {
+				fmt.Fprintf(app.Out, "undone #%d %s  %s\n", entry.Seq, app.local(entry.Time).Format(datetimeFormat), entry.Command)
+			}
+		},
+	}
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "undo even if the intervals were changed afterwards")
+	return cmd
+}
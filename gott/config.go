@@ -0,0 +1,101 @@
+package gott
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	ConfTimezone    = "timezone"
+	ConfDayBoundary = "dayboundary"
+	ConfBulk        = "bulk"
+	ConfEditor      = "editor"
+	ConfBackups     = "backups"
+)
+
+// Config holds the settings of gott. Use ReadConfig to read them from the
+// gottrc file and the environment.
+type Config struct {
+	DatabaseName string
+	DatabaseType string
+	LockTimeout  time.Duration
+	// Location is used to bucket days, weeks and months and to show times
+	Location *time.Location
+	// DayBoundary is the time after midnight the working day starts
+	DayBoundary time.Duration
+	// Bulk is the number of intervals a command changes without confirmation
+	Bulk int
+	// Editor is the command edit runs. If empty $VISUAL or $EDITOR is used
+	Editor string
+	// Backups is the number of previous versions kept of a json database
+	Backups int
+	// UDAs are the declared user defined attributes
+	UDAs UDAs
+}
+
+// DefaultConfig returns the config used if nothing is configured.
+func DefaultConfig() Config {
+	return Config{
+		DatabaseName: "db.json",
+		LockTimeout:  5 * time.Second,
+		Location:     time.Local,
+		Bulk:         3,
+		Backups:      defaultBackups,
+	}
+}
+
+// ReadConfig reads the gottrc file from the current directory or
+// $XDG_CONFIG_HOME/gott/ and the environment. A missing or broken config file
+// is returned as error together with the config read from the remaining
+// sources.
+func ReadConfig() (Config, error) {
+	defaults := DefaultConfig()
+
+	v := viper.New()
+	v.SetConfigName("gottrc")
+	v.AddConfigPath(".")
+	v.AddConfigPath("$XDG_CONFIG_HOME/gott/")
+
+	v.SetDefault(ConfDatabaseName, defaults.DatabaseName)
+	v.SetDefault(ConfLockTimeout, defaults.LockTimeout)
+	v.SetDefault(ConfBulk, defaults.Bulk)
+	v.SetDefault(ConfBackups, defaults.Backups)
+
+	v.AutomaticEnv()
+	// $EDITOR is looked up after $VISUAL when the editor is run
+	v.BindEnv(ConfEditor, "GOTT_EDITOR")
+
+	err := v.ReadInConfig()
+
+	config := Config{
+		DatabaseName: v.GetString(ConfDatabaseName),
+		DatabaseType: v.GetString(ConfDatabaseType),
+		LockTimeout:  v.GetDuration(ConfLockTimeout),
+		Location:     defaults.Location,
+		Bulk:         v.GetInt(ConfBulk),
+		Editor:       v.GetString(ConfEditor),
+		Backups:      v.GetInt(ConfBackups),
+	}
+	if tz := v.GetString(ConfTimezone); tz != "" {
+		if loc, tzErr := time.LoadLocation(tz); tzErr != nil {
+			err = fmt.Errorf("invalid %s %s, using the local timezone: %s", ConfTimezone, tz, tzErr.Error())
+		} else {
+			config.Location = loc
+		}
+	}
+	udas, udaErr := readUDAs(v.Get)
+	config.UDAs = udas
+	if udaErr != nil {
+		err = udaErr
+	}
+	if boundary := v.GetString(ConfDayBoundary); boundary != "" {
+		if d, boundaryErr := parseDayBoundary(boundary); boundaryErr != nil {
+			err = boundaryErr
+		} else {
+			config.DayBoundary = d
+		}
+	}
+	return config, err
+}
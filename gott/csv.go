@@ -0,0 +1,334 @@
+package gott
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	uuid "github.com/nu7hatch/gouuid"
+)
+
+// The columns of csv files. Declared udas are columns too.
+const (
+	CSVColumnID         = "id"
+	CSVColumnDate       = "date"
+	CSVColumnBegin      = "begin"
+	CSVColumnEnd        = "end"
+	CSVColumnDuration   = "duration"
+	CSVColumnHours      = "hours"
+	CSVColumnMinutes    = "minutes"
+	CSVColumnProject    = "project"
+	CSVColumnTags       = "tags"
+	CSVColumnRef        = "ref"
+	CSVColumnAnnotation = "annotation"
+)
+
+var CSVColumns = []string{CSVColumnID, CSVColumnDate, CSVColumnBegin, CSVColumnEnd, CSVColumnDuration,
+	CSVColumnHours, CSVColumnMinutes, CSVColumnProject, CSVColumnTags, CSVColumnRef, CSVColumnAnnotation}
+
+var DefaultCSVColumns = []string{CSVColumnID, CSVColumnDate, CSVColumnBegin, CSVColumnEnd, CSVColumnDuration,
+	CSVColumnProject, CSVColumnTags, CSVColumnRef, CSVColumnAnnotation}
+
+// csvFormat describes a csv file. Columns are the columns written by
+// writeCSV, Headers maps columns to other headers like date to Datum.
+type csvFormat struct {
+	Columns   []string
+	Headers   map[string]string
+	Delimiter rune
+	UDAs      UDAs
+}
+
+// newCSVFormat checks the columns, the mapping like date=Datum and the
+// delimiter given on the command line.
+func newCSVFormat(columns, mapping []string, delimiter string, udas UDAs) (csvFormat, error) {
+	f := csvFormat{Columns: columns, Headers: map[string]string{}, UDAs: udas}
+	for _, column := range columns {
+		if !f.known(column) {
+			return f, fmt.Errorf("unknown column %s. use one of %s or a declared uda", column, strings.Join(CSVColumns, ", "))
+		}
+	}
+	for _, m := range mapping {
+		idx := strings.Index(m, "=")
+		if idx <= 0 || idx == len(m)-1 {
+			return f, fmt.Errorf("invalid mapping %s. use COLUMN=HEADER", m)
+		}
+		column, header := strings.TrimSpace(m[:idx]), strings.TrimSpace(m[idx+1:])
+		if !f.known(column) {
+			return f, fmt.Errorf("unknown column %s in mapping %s", column, m)
+		}
+		f.Headers[column] = header
+	}
+
+	switch delimiter {
+	case "tab", `\t`:
+		f.Delimiter = '\t'
+	default:
+		r, size := utf8.DecodeRuneInString(delimiter)
+		if size == 0 || size != len(delimiter) || r == '"' || r == '\n' || r == '\r' {
+			return f, fmt.Errorf("invalid delimiter %q. use one character or tab", delimiter)
+		}
+		f.Delimiter = r
+	}
+	return f, nil
+}
+
+func (f csvFormat) known(column string) bool {
+	if containsString(CSVColumns, column) {
+		return true
+	}
+	_, found := f.UDAs.Get(column)
+	return found
+}
+
+func (f csvFormat) header(column string) string {
+	if header, found := f.Headers[column]; found {
+		return header
+	}
+	return column
+}
+
+// writeCSV writes the intervals with a header line. Times are HH:MM of the
+// working day in date, durations are HH:MM, decimal hours or minutes.
+// Duration only intervals have no begin and end, running ones no end and
+// duration.
+func writeCSV(w io.Writer, intervals []*Interval, f csvFormat, cal Calendar) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = f.Delimiter
+	var header []string
+	for _, column := range f.Columns {
+		header = append(header, f.header(column))
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, i := range intervals {
+		var record []string
+		for _, column := range f.Columns {
+			record = append(record, csvValue(i, column, cal))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvValue(i *Interval, column string, cal Calendar) string {
+	loc := cal.Now().Location()
+	begin := i.Begin.In(loc)
+	day := cal.Day(begin)
+	durationOnly := i.End.Equal(i.Begin)
+	running := i.End.IsZero()
+	duration := i.GetDuration(cal.Now()).Round(time.Minute)
+
+	switch column {
+	case CSVColumnID:
+		return i.ID
+	case CSVColumnDate:
+		return day.Format(dateFormat)
+	case CSVColumnBegin:
+		if durationOnly && begin.Equal(cal.DayStart(day)) {
+			return ""
+		}
+		return begin.Format(timeFormat)
+	case CSVColumnEnd:
+		if durationOnly || running {
+			return ""
+		}
+		return i.End.In(loc).Format(timeFormat)
+	case CSVColumnDuration, CSVColumnHours, CSVColumnMinutes:
+		if running {
+			return ""
+		}
+		switch column {
+		case CSVColumnHours:
+			return strconv.FormatFloat(duration.Hours(), 'f', 2, 64)
+		case CSVColumnMinutes:
+			return strconv.Itoa(int(duration / time.Minute))
+		}
+		return fmtDuration(duration)
+	case CSVColumnProject:
+		return i.Project
+	case CSVColumnTags:
+		return strings.Join(i.Tags, " ")
+	case CSVColumnRef:
+		return i.Ref
+	case CSVColumnAnnotation:
+		return i.Annotation
+	}
+	if value, found := i.UDA[column]; found {
+		return fmtUDAValue(value)
+	}
+	return ""
+}
+
+// csvRecord is a line of a csv file with the values by column.
+type csvRecord struct {
+	Line   int
+	Values map[string]string
+}
+
+// readCSV reads the records of a csv file. The columns are found by their
+// header, mapped headers must be in the file. The date column is required.
+func readCSV(r io.Reader, f csvFormat) ([]csvRecord, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = f.Delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := append([]string{}, CSVColumns...)
+	for _, uda := range f.UDAs {
+		columns = append(columns, uda.Name)
+	}
+	index := map[string]int{}
+	for _, column := range columns {
+		for n, name := range header {
+			if strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")), f.header(column)) {
+				index[column] = n
+				break
+			}
+		}
+		if _, found := index[column]; !found && f.Headers[column] != "" {
+			return nil, fmt.Errorf("column %s of %s is missing", f.Headers[column], column)
+		}
+	}
+	if _, found := index[CSVColumnDate]; !found {
+		return nil, fmt.Errorf("column %s is missing. map it with --map %s=HEADER", CSVColumnDate, CSVColumnDate)
+	}
+
+	var records []csvRecord
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		record := csvRecord{Line: line, Values: map[string]string{}}
+		empty := true
+		for column, n := range index {
+			if n < len(fields) {
+				record.Values[column] = strings.TrimSpace(fields[n])
+				empty = empty && record.Values[column] == ""
+			}
+		}
+		if !empty {
+			records = append(records, record)
+		}
+	}
+}
+
+// toInterval creates the interval of the record like track does. Without an
+// id column the id is derived from the values, so a second import finds the
+// interval.
+func (r csvRecord) toInterval(cal Calendar, udas UDAs) (Interval, error) {
+	v := r.Values
+	interval := NewInterval(nil)
+	args := strings.Fields(v[CSVColumnDate])
+	if len(args) == 0 {
+		return interval, errors.New("the date is missing")
+	}
+	duration, err := r.duration()
+	if err != nil {
+		return interval, err
+	}
+	switch {
+	case v[CSVColumnBegin] != "" && v[CSVColumnEnd] != "":
+		args = append(args, v[CSVColumnBegin]+TrackTimeSep+v[CSVColumnEnd])
+	case v[CSVColumnBegin] != "" && duration > 0:
+		args = append(args, v[CSVColumnBegin], TrackFor, duration.String())
+	case duration > 0:
+		args = append(args, duration.String())
+	default:
+		return interval, errors.New("needs a begin and end or a duration")
+	}
+	if err := lexTrack(args, &interval, cal, udas); err != nil {
+		return interval, errors.New(strings.TrimPrefix(err.Error(), "ERROR: "))
+	}
+
+	// the values are taken as they are, an annotation like proj:x stays text
+	interval.Project = v[CSVColumnProject]
+	interval.Ref = v[CSVColumnRef]
+	interval.Annotation = v[CSVColumnAnnotation]
+	for _, tag := range strings.Fields(v[CSVColumnTags]) {
+		if tag = strings.TrimPrefix(tag, TagPrefix); tag != "" {
+			interval.Tags = append(interval.Tags, tag)
+		}
+	}
+	for _, uda := range udas {
+		if v[uda.Name] == "" {
+			continue
+		}
+		value, err := uda.Parse(v[uda.Name])
+		if err != nil {
+			return interval, err
+		}
+		setUDA(&interval, uda.Name, value)
+	}
+	udas.setDefaults(&interval)
+	interval.Raw = formatRaw(&interval)
+
+	if v[CSVColumnID] != "" {
+		interval.ID = v[CSVColumnID]
+	} else {
+		interval.ID = csvID(&interval)
+	}
+	return interval, interval.Validate()
+}
+
+// duration reads the duration of the record from the duration column in
+// HH:MM or like 1h30m, the decimal hours or the minutes. A decimal comma is
+// accepted.
+func (r csvRecord) duration() (time.Duration, error) {
+	if text := r.Values[CSVColumnDuration]; text != "" {
+		if idx := strings.Index(text, ":"); idx > 0 {
+			h, errH := strconv.Atoi(text[:idx])
+			m, errM := strconv.Atoi(text[idx+1:])
+			if errH != nil || errM != nil || h < 0 || m < 0 || m > 59 {
+				return 0, fmt.Errorf("invalid duration %s. use HH:MM or like 1h30m", text)
+			}
+			return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+		}
+		d, err := time.ParseDuration(text)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("invalid duration %s. use HH:MM or like 1h30m", text)
+		}
+		return d, nil
+	}
+	units := map[string]time.Duration{CSVColumnHours: time.Hour, CSVColumnMinutes: time.Minute}
+	for _, column := range []string{CSVColumnHours, CSVColumnMinutes} {
+		text, unit := r.Values[column], units[column]
+		if text == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.Replace(text, ",", ".", 1), 64)
+		if err != nil || f < 0 {
+			return 0, fmt.Errorf("invalid %s %s", column, text)
+		}
+		return time.Duration(f * float64(unit)).Round(time.Second), nil
+	}
+	return 0, nil
+}
+
+// csvID derives the id of an imported interval from its values.
+func csvID(i *Interval) string {
+	key := strings.Join([]string{i.Begin.UTC().Format(time.RFC3339Nano), i.End.UTC().Format(time.RFC3339Nano),
+		i.Duration.String(), i.Project, strings.Join(i.Tags, " "), i.Ref, i.Annotation, fmtUDA(i.UDA)}, "\x00")
+	id, _ := uuid.NewV5(uuid.NamespaceURL, []byte("csv:"+key))
+	return id.String()
+}
@@ -0,0 +1,115 @@
+package gott
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCSVFormat(t *testing.T) {
+	f, err := newCSVFormat([]string{"date", "hours", "estimate"}, []string{"date=Datum", "hours = Stunden"}, "tab", testUDAs)
+	assert.NoError(t, err)
+	assert.Equal(t, '\t', f.Delimiter)
+	assert.Equal(t, "Stunden", f.header("hours"))
+	assert.Equal(t, "estimate", f.header("estimate"))
+
+	for _, args := range [][]string{{"colour", "", ","}, {"date", "date", ","}, {"date", "colour=Farbe", ","}, {"date", "", ";;"}, {"date", "", ""}} {
+		_, err := newCSVFormat([]string{args[0]}, strings.Fields(args[1]), args[2], testUDAs)
+		assert.Error(t, err, args)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	cal := Calendar{Clock: FixedClock{time.Date(2022, 1, 20, 12, 0, 0, 0, time.UTC)}}
+	f, err := newCSVFormat(nil, []string{"date=Datum", "hours=Stunden", "annotation=Text"}, ";", testUDAs)
+	assert.NoError(t, err)
+	records, err := readCSV(strings.NewReader(`Datum;Begin;End;Stunden;Project;Tags;Text;Estimate
+2022-01-14;09:00;10:30;;gott;docs +billable;proj:x is text;2h
+;;;;;;;
+2022-01-15;;;1,5;;;"call; long";
+2022-01-16;08:00;;0,25;;;;
+`), f)
+	assert.NoError(t, err)
+	if !assert.Len(t, records, 3) {
+		return
+	}
+	assert.Equal(t, 4, records[1].Line)
+
+	i, err := records[0].toInterval(cal, testUDAs)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 1, 14, 9, 0, 0, 0, time.UTC), i.Begin)
+	assert.Equal(t, time.Date(2022, 1, 14, 10, 30, 0, 0, time.UTC), i.End)
+	assert.Equal(t, "gott", i.Project)
+	assert.Equal(t, []string{"docs", "billable"}, i.Tags)
+	assert.Equal(t, "proj:x is text", i.Annotation)
+	assert.Equal(t, map[string]interface{}{"estimate": "2h0m0s", "client": "acme"}, i.UDA)
+	assert.Equal(t, StatusEnded, i.Status)
+
+	// the same values give the same id
+	again, _ := records[0].toInterval(cal, testUDAs)
+	assert.Equal(t, i.ID, again.ID)
+
+	i, err = records[1].toInterval(cal, testUDAs)
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, i.Duration)
+	assert.Equal(t, "call; long", i.Annotation)
+
+	i, err = records[2].toInterval(cal, testUDAs)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 1, 16, 8, 15, 0, 0, time.UTC), i.End)
+
+	for _, text := range []string{"date\nsoon", "date,duration\n2022-01-14,1:75", "date,hours\n2022-01-14,many", "date,begin,end\n2022-01-14,09:00,", "date,duration,estimate\n2022-01-14,1h,soon"} {
+		f, _ := newCSVFormat(nil, nil, ",", testUDAs)
+		records, err := readCSV(strings.NewReader(text), f)
+		if assert.NoError(t, err, text) && assert.Len(t, records, 1, text) {
+			_, err = records[0].toInterval(cal, testUDAs)
+			assert.Error(t, err, text)
+		}
+	}
+	_, err = readCSV(strings.NewReader("Datum,hours\n"), csvFormat{Delimiter: ','})
+	assert.Error(t, err)
+	_, err = readCSV(strings.NewReader("date\n"), csvFormat{Delimiter: ',', Headers: map[string]string{"hours": "Stunden"}})
+	assert.Error(t, err)
+}
+
+func TestAppCSV(t *testing.T) {
+	app, out := newTestApp(t)
+	app.Config.Location = time.UTC
+	runApp(t, app, "track", "2022-01-14", "09:00-10:30", "--", "proj:gott", "+docs", "writing, docs")
+	runApp(t, app, "track", "2022-01-14", "2h", "--", "review")
+
+	out.Reset()
+	runApp(t, app, "export", "--format", "csv", "--columns", "date,begin,end,hours,project,tags,annotation", "--map", "date=Datum,hours=Stunden", "--delimiter", ";")
+	exported := out.String()
+	assert.Equal(t, `Datum;begin;end;Stunden;project;tags;annotation
+2022-01-14;;;2.00;;;review
+2022-01-14;09:00;10:30;1.50;gott;docs;writing, docs
+`, exported)
+
+	// the export imports into another database and a second import skips it
+	file := filepath.Join(t.TempDir(), "export.csv")
+	assert.NoError(t, ioutil.WriteFile(file, []byte(exported), 0600))
+	other, out := newTestApp(t)
+	other.Config.Location = time.UTC
+	args := []string{"import", "csv", file, "--map", "date=Datum,hours=Stunden", "--delimiter", ";"}
+	runApp(t, other, append(args, "--dry-run")...)
+	assert.Contains(t, out.String(), "would import 2 intervals")
+
+	out.Reset()
+	runApp(t, other, args...)
+	assert.Contains(t, out.String(), "imported 2 intervals, skipped 0")
+	intervals, _ := other.Database.Filter([]string{KeyAll, "proj:gott"})
+	if assert.Len(t, intervals, 1) {
+		assert.Equal(t, "writing, docs", intervals[0].Annotation)
+		assert.Equal(t, []string{"docs"}, intervals[0].Tags)
+	}
+
+	out.Reset()
+	runApp(t, other, args...)
+	assert.Contains(t, out.String(), "imported 0 intervals, skipped 2 imported before")
+	assert.Equal(t, 2, other.Database.Count())
+}
@@ -6,26 +6,47 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 )
 
 const (
 	ConfDatabaseName = "databasename"
+	ConfDatabaseType = "databasetype"
 	databseFilename  = "db.json"
 )
 
+const (
+	DatabaseTypeJson   = "json"
+	DatabaseTypeSqlite = "sqlite"
+)
+
 type Database interface {
 	GetCurrent() (*Interval, bool)
 	Get(id string) (*Interval, bool)
-	Start(interval Interval)
+	// CurrentID returns the id of the current interval, even if it does not
+	// exist
+	CurrentID() string
+	SetCurrent(id string) error
+	// Start begins the interval at the time at and stops the current one
+	Start(interval Interval, at time.Time)
 	Cancel()
-	Stop()
-	Append(interval Interval)
-	AppendPtr(interval *Interval)
+	// Stop ends the current interval at the time at
+	Stop(at time.Time)
+	// Append and AppendPtr add a new valid interval
+	Append(interval Interval) error
+	AppendPtr(interval *Interval) error
 	RemoveById(id string)
 	// Remove(interval *Interval)
 	Filter(args []string) ([]*Interval, error)
+	// Overlapping returns the intervals which may overlap the time from begin
+	// until end, sorted by begin
+	Overlapping(begin, end time.Time) ([]*Interval, error)
+	// IDs returns the ids of all intervals sorted by begin
+	IDs() ([]string, error)
+	// Apply validates the interval and copies it to the existing one
 	Apply(interval Interval) error
 	Load() error
 	Save() error
@@ -34,15 +55,24 @@ type Database interface {
 }
 
 type DatabaseJson struct {
-	filename  string
-	Current   string
-	Intervals []*Interval
+	filename      string
+	calendar      Calendar
+	udas          UDAs
+	backups       int
+	migrated      []string
+	SchemaVersion int
+	Current       string
+	Intervals     []*Interval
 }
 
 func (d *DatabaseJson) GetCurrent() (*Interval, bool) {
 	return d.Get(d.Current)
 }
 
+func (d *DatabaseJson) CurrentID() string {
+	return d.Current
+}
+
 func (d *DatabaseJson) Get(id string) (*Interval, bool) {
 	for _, i := range d.Intervals {
 		if i.ID == id {
@@ -52,12 +82,20 @@ func (d *DatabaseJson) Get(id string) (*Interval, bool) {
 	return nil, false
 }
 
-func (d *DatabaseJson) Start(interval Interval) {
-	interval.Begin = time.Now()
+func (d *DatabaseJson) SetCurrent(id string) error {
+	if _, found := d.Get(id); id != "" && !found {
+		return fmt.Errorf("Interval with id %s does not exist", id)
+	}
+	d.Current = id
+	return nil
+}
+
+func (d *DatabaseJson) Start(interval Interval, at time.Time) {
+	interval.Begin = at
 	interval.Status = StatusStarted
 	d.Intervals = append(d.Intervals, &interval)
 	if d.Current != "" {
-		d.Stop()
+		d.Stop(at)
 	}
 	d.Current = interval.ID
 }
@@ -74,74 +112,64 @@ func (d *DatabaseJson) Cancel() {
 	}
 }
 
-func (d *DatabaseJson) Stop() {
+func (d *DatabaseJson) Stop(at time.Time) {
 	for _, interval := range d.Intervals {
 		if interval.ID == d.Current {
 			d.Current = ""
-			interval.Stop()
+			interval.Stop(at)
 			break
 		}
 	}
 }
 
-func (d *DatabaseJson) Append(interval Interval) {
-	d.AppendPtr(&interval)
+func (d *DatabaseJson) Append(interval Interval) error {
+	return d.AppendPtr(&interval)
 }
 
-func (d *DatabaseJson) AppendPtr(interval *Interval) {
+func (d *DatabaseJson) AppendPtr(interval *Interval) error {
+	if err := interval.Validate(); err != nil {
+		return err
+	}
+	if _, found := d.Get(interval.ID); found {
+		return fmt.Errorf("Interval with id %s already exists", interval.ID)
+	}
 	d.Intervals = append(d.Intervals, interval)
+	return nil
 }
 
+// RemoveById removes all intervals with the id, duplicates included.
 func (d *DatabaseJson) RemoveById(id string) {
-	// TODO optimize
-	for i, interval := range d.Intervals {
-		if interval.ID == id {
-			d.Intervals = append(d.Intervals[:i], d.Intervals[i+1:]...)
+	kept := d.Intervals[:0]
+	for _, interval := range d.Intervals {
+		if interval.ID != id {
+			kept = append(kept, interval)
 		}
 	}
+	d.Intervals = kept
 }
 
 func (d *DatabaseJson) Filter(args []string) ([]*Interval, error) {
-	var resultSet []*Interval
+	filter, err := parseFilter(args, d.calendar, d.udas)
+	if err != nil {
+		return nil, err
+	}
+	return d.filter(filter), nil
+}
 
-	filterList := []filterFunc{}
-
-	if len(args) > 0 {
-		for _, arg := range args {
-			switch arg {
-			case KeyToday:
-				filterList = append(filterList, createDateFilter(time.Now()))
-				continue
-			case KeyYesterday:
-				yesterday := time.Now().AddDate(0, 0, -1)
-				filterList = append(filterList, createDateFilter(yesterday))
-				continue
-			case KeyWeek:
-				begin := time.Now()
-				for begin.Weekday() != time.Monday {
-					begin = begin.AddDate(0, 0, -1)
-				}
-				filterList = append(filterList, createDateRangeFilter(begin, time.Now()))
-				continue
-			case KeyMonth:
-				begin := time.Now()
-				for begin.Day() != 1 {
-					begin = begin.AddDate(0, 0, -1)
-				}
-				filterList = append(filterList, createDateRangeFilter(begin, time.Now()))
-				continue
-			case KeyAll:
-				continue
-			default:
-				if t, err := time.Parse("2006-01-02", arg); err != nil {
-					return resultSet, fmt.Errorf("invalid summary filter %s, %s", arg, err.Error())
-				} else {
-					filterList = append(filterList, createDateFilter(t))
-				}
-				continue
-			}
-		}
+func (d *DatabaseJson) Overlapping(begin, end time.Time) ([]*Interval, error) {
+	return d.filter(overlapExpr(begin, end).match), nil
+}
+
+func (d *DatabaseJson) IDs() ([]string, error) {
+	var ids []string
+	for _, i := range d.filter(func(*Interval) bool { return true }) {
+		ids = append(ids, i.ID)
 	}
+	return ids, nil
+}
+
+func (d *DatabaseJson) filter(filter filterFunc) []*Interval {
+	var resultSet []*Interval
 
 	// sort by date
 	sort.SliceStable(d.Intervals, func(i, j int) bool {
@@ -151,15 +179,18 @@ func (d *DatabaseJson) Filter(args []string) ([]*Interval, error) {
 	})
 
 	for _, interval := range d.Intervals {
-		if applyFilter(interval, filterList) {
+		if filter(interval) {
 			resultSet = append(resultSet, interval)
 		}
 	}
 
-	return resultSet, nil
+	return resultSet
 }
 
 func (d *DatabaseJson) Apply(i Interval) error {
+	if err := i.Validate(); err != nil {
+		return err
+	}
 	e, found := d.Get(i.ID)
 	if !found {
 		return fmt.Errorf("Interval with id %s does not exist", i.ID)
@@ -182,25 +213,38 @@ func (d *DatabaseJson) Count() int {
 }
 
 func (d *DatabaseJson) Save() error {
-	data, _ := json.Marshal(d)
-	ioutil.WriteFile(d.filename, data, 0644)
-	return nil
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("error marshaling database: %s", err.Error())
+	}
+	return writeFileAtomic(d.filename, data, 0644, d.backups)
 }
 
 func (d *DatabaseJson) Load() error {
 	if _, err := os.Stat(d.filename); errors.Is(err, os.ErrNotExist) {
-		d.Save()
+		if err := d.Save(); err != nil {
+			return err
+		}
 	}
-	if file, errFile := ioutil.ReadFile(d.filename); errFile != nil {
+	file, errFile := ioutil.ReadFile(d.filename)
+	if errFile != nil {
 		return fmt.Errorf("error reading file. %s", errFile.Error())
-	} else {
-		if unmarshalErr := json.Unmarshal([]byte(file), &d); unmarshalErr != nil {
-			return fmt.Errorf("error unmashaling database file: %s", unmarshalErr.Error())
-		}
+	}
+	file, migrated, errMigrate := migrateJson(file, d.calendar)
+	if errMigrate != nil {
+		return fmt.Errorf("error migrating database file: %s", errMigrate.Error())
+	}
+	d.migrated = migrated
+	if unmarshalErr := json.Unmarshal(file, &d); unmarshalErr != nil {
+		return fmt.Errorf("error unmashaling database file: %s", unmarshalErr.Error())
 	}
 	return nil
 }
 
+func (d *DatabaseJson) Migrated() []string {
+	return d.migrated
+}
+
 func (d *DatabaseJson) Latest() (*Interval, error) {
 	if length := d.Count(); length > 0 {
 		return d.Intervals[length-1], nil
@@ -209,8 +253,36 @@ func (d *DatabaseJson) Latest() (*Interval, error) {
 	}
 }
 
-func NewDatabaseJson(filename string) *DatabaseJson {
+func NewDatabaseJson(filename string, calendar Calendar) *DatabaseJson {
 	return &DatabaseJson{
-		filename: filename,
+		filename:      filename,
+		calendar:      calendar,
+		backups:       defaultBackups,
+		SchemaVersion: SchemaVersion,
+	}
+}
+
+// NewDatabase creates the database for the given file. If dbtype is empty the
+// type is guessed by the file extension. Filters may use the declared udas.
+func NewDatabase(filename, dbtype string, calendar Calendar, udas UDAs) (Database, error) {
+	if dbtype == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".db", ".sqlite", ".sqlite3":
+			dbtype = DatabaseTypeSqlite
+		default:
+			dbtype = DatabaseTypeJson
+		}
+	}
+	switch dbtype {
+	case DatabaseTypeJson:
+		db := NewDatabaseJson(filename, calendar)
+		db.udas = udas
+		return db, nil
+	case DatabaseTypeSqlite:
+		db := NewDatabaseSqlite(filename, calendar)
+		db.udas = udas
+		return db, nil
+	default:
+		return nil, fmt.Errorf("unknown database type %s. Choose one of %s or %s", dbtype, DatabaseTypeJson, DatabaseTypeSqlite)
 	}
 }
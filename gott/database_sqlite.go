@@ -0,0 +1,572 @@
+package gott
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS intervals (
+	id         TEXT PRIMARY KEY,
+	begin_at   INTEGER,
+	end_at     INTEGER,
+	duration   INTEGER NOT NULL DEFAULT 0,
+	project    TEXT NOT NULL DEFAULT '',
+	ref        TEXT NOT NULL DEFAULT '',
+	annotation TEXT NOT NULL DEFAULT '',
+	raw        TEXT NOT NULL DEFAULT '',
+	uda        TEXT,
+	status     TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS intervals_begin ON intervals (begin_at);
+CREATE INDEX IF NOT EXISTS intervals_project ON intervals (project);
+CREATE INDEX IF NOT EXISTS intervals_ref ON intervals (ref);
+
+CREATE TABLE IF NOT EXISTS tags (
+	interval_id TEXT NOT NULL,
+	position    INTEGER NOT NULL,
+	tag         TEXT NOT NULL,
+	PRIMARY KEY (interval_id, position)
+);
+CREATE INDEX IF NOT EXISTS tags_tag ON tags (tag);
+
+CREATE TABLE IF NOT EXISTS state (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+`
+
+// sqliteSchemaVersion is stored as user_version of the sqlite database.
+const sqliteSchemaVersion = 2
+
+// sqliteMigration upgrades the sqlite database from one schema version to
+// the next.
+type sqliteMigration struct {
+	description string
+	migrate     func(tx *sql.Tx, cal Calendar) error
+}
+
+// sqliteMigrations[v-1] migrates version v to version v+1. New files are
+// created with the current schema.
+var sqliteMigrations = []sqliteMigration{
+	{
+		description: migrateDurationOnlyDescription,
+		migrate:     migrateSqliteDurationOnly,
+	},
+}
+
+func migrateSqliteDurationOnly(tx *sql.Tx, cal Calendar) error {
+	rows, err := tx.Query("SELECT id, begin_at FROM intervals WHERE begin_at = end_at AND begin_at IS NOT NULL AND duration != 0")
+	if err != nil {
+		return err
+	}
+	moved := map[string]int64{}
+	for rows.Next() {
+		var id string
+		var begin int64
+		if err := rows.Scan(&id, &begin); err != nil {
+			rows.Close()
+			return err
+		}
+		moved[id] = anchorDurationOnly(time.Unix(0, begin).In(cal.Now().Location()), cal).UnixNano()
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, begin := range moved {
+		if _, err := tx.Exec("UPDATE intervals SET begin_at = ?, end_at = ? WHERE id = ?", begin, begin, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const sqliteIntervalColumns = "id, begin_at, end_at, duration, project, ref, annotation, raw, uda, status"
+
+const stateCurrent = "current"
+
+// DatabaseSqlite stores the intervals in a sqlite database. All changes are
+// made in one transaction, which Save commits and Close rolls back. Intervals
+// handed out by the database are cached, so changes made to them are written
+// back on Save like they are for DatabaseJson. Only the intervals which
+// differ from the stored ones are written.
+type DatabaseSqlite struct {
+	filename  string
+	calendar  Calendar
+	udas      UDAs
+	db        *sql.DB
+	tx        *sql.Tx
+	intervals map[string]*Interval
+	// stored are copies of the cached intervals as they are in the database
+	stored   map[string]*Interval
+	migrated []string
+	err      error
+}
+
+// fail remembers the first error. The Database interface does not return
+// errors for most mutations, so it is reported on Save.
+func (d *DatabaseSqlite) fail(err error) {
+	if err != nil && d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *DatabaseSqlite) GetCurrent() (*Interval, bool) {
+	current := d.CurrentID()
+	if current == "" {
+		return nil, false
+	}
+	return d.Get(current)
+}
+
+func (d *DatabaseSqlite) CurrentID() string {
+	var current string
+	err := d.tx.QueryRow("SELECT value FROM state WHERE key = ?", stateCurrent).Scan(&current)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		d.fail(err)
+	}
+	return current
+}
+
+func (d *DatabaseSqlite) SetCurrent(id string) error {
+	if id == "" {
+		_, err := d.tx.Exec("DELETE FROM state WHERE key = ?", stateCurrent)
+		return err
+	}
+	if _, found := d.Get(id); !found {
+		return fmt.Errorf("Interval with id %s does not exist", id)
+	}
+	_, err := d.tx.Exec(
+		"INSERT INTO state (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value",
+		stateCurrent, id,
+	)
+	return err
+}
+
+func (d *DatabaseSqlite) Get(id string) (*Interval, bool) {
+	if i, found := d.intervals[id]; found {
+		return i, true
+	}
+	result, err := d.query("SELECT "+sqliteIntervalColumns+" FROM intervals WHERE id = ?", id)
+	if err != nil {
+		d.fail(err)
+		return nil, false
+	}
+	if len(result) == 0 {
+		return nil, false
+	}
+	return result[0], true
+}
+
+func (d *DatabaseSqlite) Start(interval Interval, at time.Time) {
+	interval.Begin = at
+	interval.Status = StatusStarted
+	if _, found := d.GetCurrent(); found {
+		d.Stop(at)
+	}
+	d.fail(d.AppendPtr(&interval))
+	d.fail(d.SetCurrent(interval.ID))
+}
+
+func (d *DatabaseSqlite) Cancel() {
+	if cur, found := d.GetCurrent(); found {
+		d.RemoveById(cur.ID)
+		d.fail(d.SetCurrent(""))
+	}
+}
+
+func (d *DatabaseSqlite) Stop(at time.Time) {
+	if cur, found := d.GetCurrent(); found {
+		cur.Stop(at)
+		d.fail(d.write(cur))
+		d.fail(d.SetCurrent(""))
+	}
+}
+
+func (d *DatabaseSqlite) Append(interval Interval) error {
+	return d.AppendPtr(&interval)
+}
+
+func (d *DatabaseSqlite) AppendPtr(interval *Interval) error {
+	if err := interval.Validate(); err != nil {
+		return err
+	}
+	if _, found := d.Get(interval.ID); found {
+		return fmt.Errorf("Interval with id %s already exists", interval.ID)
+	}
+	d.intervals[interval.ID] = interval
+	return d.write(interval)
+}
+
+func (d *DatabaseSqlite) RemoveById(id string) {
+	delete(d.intervals, id)
+	delete(d.stored, id)
+	if _, err := d.tx.Exec("DELETE FROM tags WHERE interval_id = ?", id); err != nil {
+		d.fail(err)
+		return
+	}
+	_, err := d.tx.Exec("DELETE FROM intervals WHERE id = ?", id)
+	d.fail(err)
+}
+
+// Filter selects the candidates with the sql condition of the filter and
+// checks the parts sql can not express, like the annotation text, in Go.
+func (d *DatabaseSqlite) Filter(args []string) ([]*Interval, error) {
+	filter, err := parseFilterExpr(args, d.calendar, d.udas)
+	if err != nil {
+		return nil, err
+	}
+	return d.filter(filter)
+}
+
+func (d *DatabaseSqlite) Overlapping(begin, end time.Time) ([]*Interval, error) {
+	return d.filter(overlapExpr(begin, end))
+}
+
+// IDs selects only the ids, so handles are numbered without loading the
+// intervals.
+func (d *DatabaseSqlite) IDs() ([]string, error) {
+	if err := d.flush(); err != nil {
+		return nil, err
+	}
+	rows, err := d.tx.Query("SELECT id FROM intervals ORDER BY begin_at, rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (d *DatabaseSqlite) filter(filter filterExpr) ([]*Interval, error) {
+	var resultSet []*Interval
+
+	// the condition must see the changes made to cached intervals
+	if err := d.flush(); err != nil {
+		return resultSet, err
+	}
+
+	intervals, err := d.query("SELECT "+sqliteIntervalColumns+" FROM intervals WHERE "+filter.cond+" ORDER BY begin_at, rowid", filter.args...)
+	if err != nil {
+		return resultSet, err
+	}
+
+	for _, interval := range intervals {
+		if filter.match(interval) {
+			resultSet = append(resultSet, interval)
+		}
+	}
+	// cached intervals may begin at another time than stored
+	sort.SliceStable(resultSet, func(i, j int) bool { return resultSet[i].Begin.Before(resultSet[j].Begin) })
+
+	return resultSet, nil
+}
+
+func (d *DatabaseSqlite) Apply(i Interval) error {
+	if err := i.Validate(); err != nil {
+		return err
+	}
+	e, found := d.Get(i.ID)
+	if !found {
+		return fmt.Errorf("Interval with id %s does not exist", i.ID)
+	}
+	e.Begin = i.Begin
+	e.End = i.End
+	e.Duration = i.Duration
+	e.Project = i.Project
+	e.Ref = i.Ref
+	e.Tags = i.Tags
+	e.UDA = i.UDA
+	e.Annotation = i.Annotation
+	e.Status = i.Status
+	e.Raw = i.Raw
+	return d.write(e)
+}
+
+func (d *DatabaseSqlite) Count() int {
+	var count int
+	d.fail(d.tx.QueryRow("SELECT COUNT(*) FROM intervals").Scan(&count))
+	return count
+}
+
+func (d *DatabaseSqlite) Latest() (*Interval, error) {
+	result, err := d.query("SELECT " + sqliteIntervalColumns + " FROM intervals ORDER BY rowid DESC LIMIT 1")
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, errors.New("database empty. no latest")
+	}
+	return result[0], nil
+}
+
+func (d *DatabaseSqlite) Load() error {
+	db, err := sql.Open("sqlite3", d.filename)
+	if err != nil {
+		return fmt.Errorf("error opening database file. %s", err.Error())
+	}
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return fmt.Errorf("error reading database schema version: %s", err.Error())
+	}
+	// a new file has no intervals table yet. files written before the schema
+	// was versioned have user_version 0 and the schema of version 1
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'intervals'").Scan(&tables); err != nil {
+		db.Close()
+		return fmt.Errorf("error reading database schema: %s", err.Error())
+	}
+	isNew := tables == 0
+	if !isNew && version == 0 {
+		version = 1
+	}
+	if version > sqliteSchemaVersion {
+		db.Close()
+		return fmt.Errorf(
+			"database schema version %d is newer than the supported version %d. please update gott",
+			version, sqliteSchemaVersion,
+		)
+	}
+	// the schema is part of the transaction, so Close leaves the file as it was
+	tx, err := db.Begin()
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("error starting transaction: %s", err.Error())
+	}
+	if _, err := tx.Exec(sqliteSchema); err != nil {
+		tx.Rollback()
+		db.Close()
+		return fmt.Errorf("error creating database schema: %s", err.Error())
+	}
+	d.migrated = nil
+	for v := version; !isNew && v < sqliteSchemaVersion; v++ {
+		m := sqliteMigrations[v-1]
+		if err := m.migrate(tx, d.calendar); err != nil {
+			tx.Rollback()
+			db.Close()
+			return fmt.Errorf("error migrating schema version %d to %d: %s", v, v+1, err.Error())
+		}
+		d.migrated = append(d.migrated, fmt.Sprintf("v%d -> v%d: %s", v, v+1, m.description))
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", sqliteSchemaVersion)); err != nil {
+		tx.Rollback()
+		db.Close()
+		return fmt.Errorf("error writing database schema version: %s", err.Error())
+	}
+	d.db, d.tx = db, tx
+	// intervals cached by an earlier session may be outdated
+	d.intervals = make(map[string]*Interval)
+	d.stored = make(map[string]*Interval)
+	return nil
+}
+
+func (d *DatabaseSqlite) Migrated() []string {
+	return d.migrated
+}
+
+// Save writes the changed intervals handed out by the database, commits the
+// transaction and closes the database.
+func (d *DatabaseSqlite) Save() error {
+	if d.db == nil {
+		return d.err
+	}
+	d.fail(d.flush())
+	if d.err != nil {
+		d.Close()
+		return d.err
+	}
+	d.fail(d.tx.Commit())
+	d.fail(d.db.Close())
+	d.db, d.tx = nil, nil
+	return d.err
+}
+
+// Close discards the changes and closes the database.
+func (d *DatabaseSqlite) Close() error {
+	if d.db == nil {
+		return nil
+	}
+	d.tx.Rollback()
+	err := d.db.Close()
+	d.db, d.tx = nil, nil
+	return err
+}
+
+// flush writes the cached intervals which differ from the stored ones.
+func (d *DatabaseSqlite) flush() error {
+	for id, interval := range d.intervals {
+		if stored, found := d.stored[id]; found && sameInterval(stored, interval) {
+			continue
+		}
+		if err := d.write(interval); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *DatabaseSqlite) write(i *Interval) error {
+	var uda []byte
+	if i.UDA != nil {
+		var err error
+		if uda, err = json.Marshal(i.UDA); err != nil {
+			return err
+		}
+	}
+
+	tx := d.tx
+	_, err := tx.Exec(
+		"INSERT INTO intervals ("+sqliteIntervalColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) "+
+			"ON CONFLICT (id) DO UPDATE SET begin_at = excluded.begin_at, end_at = excluded.end_at, "+
+			"duration = excluded.duration, project = excluded.project, ref = excluded.ref, "+
+			"annotation = excluded.annotation, raw = excluded.raw, uda = excluded.uda, status = excluded.status",
+		i.ID, sqliteTime(i.Begin), sqliteTime(i.End), int64(i.Duration),
+		i.Project, i.Ref, i.Annotation, i.Raw, uda, i.Status,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM tags WHERE interval_id = ?", i.ID); err != nil {
+		return err
+	}
+	for pos, tag := range i.Tags {
+		if _, err := tx.Exec("INSERT INTO tags (interval_id, position, tag) VALUES (?, ?, ?)", i.ID, pos, tag); err != nil {
+			return err
+		}
+	}
+	d.stored[i.ID] = copyInterval(i)
+	return nil
+}
+
+func (d *DatabaseSqlite) query(query string, args ...interface{}) ([]*Interval, error) {
+	rows, err := d.tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*Interval
+	var missing []*Interval
+	for rows.Next() {
+		var i Interval
+		var begin, end sql.NullInt64
+		var uda []byte
+		var duration int64
+		if err := rows.Scan(&i.ID, &begin, &end, &duration, &i.Project, &i.Ref, &i.Annotation, &i.Raw, &uda, &i.Status); err != nil {
+			return nil, err
+		}
+		// prefer the cached interval, it may contain unsaved changes
+		if cached, found := d.intervals[i.ID]; found {
+			result = append(result, cached)
+			continue
+		}
+		i.Begin = fromSqliteTime(begin)
+		i.End = fromSqliteTime(end)
+		i.Duration = time.Duration(duration)
+		if uda != nil {
+			if err := json.Unmarshal(uda, &i.UDA); err != nil {
+				return nil, fmt.Errorf("error unmashaling uda of %s: %s", i.ID, err.Error())
+			}
+		}
+		d.intervals[i.ID] = &i
+		result = append(result, &i)
+		missing = append(missing, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(missing) > 0 {
+		if err := d.loadTags(missing); err != nil {
+			return nil, err
+		}
+	}
+	for _, i := range missing {
+		d.stored[i.ID] = copyInterval(i)
+	}
+	return result, nil
+}
+
+// sqliteMaxVariables is the number of ids loadTags queries at once, below
+// the limit of sqlite.
+const sqliteMaxVariables = 500
+
+// loadTags loads the tags of the intervals.
+func (d *DatabaseSqlite) loadTags(intervals []*Interval) error {
+	for len(intervals) > 0 {
+		n := len(intervals)
+		if n > sqliteMaxVariables {
+			n = sqliteMaxVariables
+		}
+		if err := d.loadTagsOf(intervals[:n]); err != nil {
+			return err
+		}
+		intervals = intervals[n:]
+	}
+	return nil
+}
+
+func (d *DatabaseSqlite) loadTagsOf(intervals []*Interval) error {
+	byID := make(map[string]*Interval, len(intervals))
+	args := make([]interface{}, 0, len(intervals))
+	for _, i := range intervals {
+		byID[i.ID] = i
+		args = append(args, i.ID)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+	rows, err := d.tx.Query("SELECT interval_id, tag FROM tags WHERE interval_id IN ("+placeholders+") ORDER BY interval_id, position", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return err
+		}
+		if i, found := byID[id]; found {
+			i.Tags = append(i.Tags, tag)
+		}
+	}
+	return rows.Err()
+}
+
+func sqliteTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UnixNano()
+}
+
+func fromSqliteTime(n sql.NullInt64) time.Time {
+	if !n.Valid {
+		return time.Time{}
+	}
+	return time.Unix(0, n.Int64)
+}
+
+func NewDatabaseSqlite(filename string, calendar Calendar) *DatabaseSqlite {
+	return &DatabaseSqlite{
+		filename:  filename,
+		calendar:  calendar,
+		intervals: make(map[string]*Interval),
+		stored:    make(map[string]*Interval),
+	}
+}
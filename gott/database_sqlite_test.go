@@ -0,0 +1,238 @@
+package gott
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqliteRoundTrip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "db.sqlite")
+
+	db := NewDatabaseSqlite(filename, Calendar{Clock: SystemClock{}})
+	assert.NoError(t, db.Load())
+
+	day := time.Date(2022, 1, 14, 0, 0, 0, 0, time.Local)
+	tracked := NewInterval([]string{"bake", "a", "cake", "+food", "+home", "proj:kitchen", "ref:ID-1"})
+	tracked.Begin = day
+	tracked.End = day
+	tracked.Duration = 3 * time.Hour
+	db.Append(tracked)
+
+	running := NewInterval([]string{"writing", "docs"})
+	db.Start(running, time.Now())
+	current, found := db.GetCurrent()
+	assert.True(t, found)
+	// changes to handed out intervals are written on save
+	lexInterval([]string{"+docs"}, current, nil)
+	assert.NoError(t, db.Save())
+
+	db = NewDatabaseSqlite(filename, Calendar{Clock: SystemClock{}})
+	assert.NoError(t, db.Load())
+	defer db.Save()
+
+	assert.Equal(t, 2, db.Count())
+
+	current, found = db.GetCurrent()
+	assert.True(t, found)
+	assert.Equal(t, running.ID, current.ID)
+	assert.Equal(t, []string{"docs"}, current.Tags)
+
+	intervals, err := db.Filter([]string{"2022-01-14"})
+	assert.NoError(t, err)
+	if assert.Len(t, intervals, 1) {
+		i := intervals[0]
+		assert.Equal(t, tracked.ID, i.ID)
+		assert.Equal(t, "bake a cake", i.Annotation)
+		assert.Equal(t, []string{"food", "home"}, i.Tags)
+		assert.Equal(t, "kitchen", i.Project)
+		assert.Equal(t, "ID-1", i.Ref)
+		assert.Equal(t, 3*time.Hour, i.GetDuration(time.Now()))
+	}
+
+	db.Cancel()
+	_, found = db.GetCurrent()
+	assert.False(t, found)
+	assert.Equal(t, 1, db.Count())
+}
+
+func TestSqliteFilter(t *testing.T) {
+	cal := Calendar{Clock: FixedClock{Time: time.Date(2022, 1, 20, 12, 0, 0, 0, time.UTC)}, DayBoundary: 4 * time.Hour}
+	jsonDB := NewDatabaseJson("", cal)
+	jsonDB.udas = testUDAs
+	sqliteDB := NewDatabaseSqlite(filepath.Join(t.TempDir(), "db.sqlite"), cal)
+	sqliteDB.udas = testUDAs
+	assert.NoError(t, sqliteDB.Load())
+	defer sqliteDB.Close()
+
+	for n, raw := range []string{
+		"writing docs proj:gott +docs ref:ID-1 estimate:1h",
+		"review proj:gott.docs +docs +billable ref:ID-2",
+		"call proj:acme ref:X-1 estimate:3h",
+		"glob proj:a?b[c] +a*b",
+		"late night",
+	} {
+		i := NewInterval(nil)
+		assert.NoError(t, lexInterval(strings.Fields(raw), &i, testUDAs))
+		i.Begin = time.Date(2022, 1, 13+n, 9, 0, 0, 0, time.UTC)
+		if n == 4 {
+			// before the day boundary, on the working day before
+			i.Begin = time.Date(2022, 1, 17, 2, 0, 0, 0, time.UTC)
+		}
+		i.End = i.Begin.Add(time.Hour)
+		i.Status = StatusEnded
+		assert.NoError(t, jsonDB.Append(i))
+		assert.NoError(t, sqliteDB.Append(i))
+	}
+
+	for _, filter := range []string{
+		":all", "proj:gott", "proj:go*", "proj:a?b[c]", "proj:a*", "+docs", "-docs :all", "+a*b", "ref:ID-*",
+		"not proj:gott", "not writing", "proj:acme or +billable", "not (proj:gott or call)", "writing or +billable",
+		"2022-01-14", "2022-01-14..2022-01-15", "..2022-01-14", "2022-01-16", "2022-01-16..", "estimate:1h..",
+		"not estimate: and not +docs", "(proj:gott and not +billable) or ref:X-*",
+	} {
+		expected, err := jsonDB.Filter(strings.Fields(filter))
+		assert.NoError(t, err, filter)
+		actual, err := sqliteDB.Filter(strings.Fields(filter))
+		assert.NoError(t, err, filter)
+		var expectedIDs, actualIDs []string
+		for _, i := range expected {
+			expectedIDs = append(expectedIDs, i.ID)
+		}
+		for _, i := range actual {
+			actualIDs = append(actualIDs, i.ID)
+		}
+		assert.Equal(t, expectedIDs, actualIDs, filter)
+	}
+
+	// a running interval and one with only a duration
+	running := NewInterval([]string{"running"})
+	running.Begin = time.Date(2022, 1, 18, 9, 0, 0, 0, time.UTC)
+	durationOnly := NewInterval([]string{"duration"})
+	durationOnly.Begin = cal.DayStart(time.Date(2022, 1, 13, 0, 0, 0, 0, time.UTC))
+	durationOnly.End, durationOnly.Duration = durationOnly.Begin, time.Hour
+	for _, i := range []Interval{running, durationOnly} {
+		assert.NoError(t, jsonDB.Append(i))
+		assert.NoError(t, sqliteDB.Append(i))
+	}
+	at := func(day, hour int) time.Time {
+		return time.Date(2022, 1, day, hour, 0, 0, 0, time.UTC)
+	}
+	for _, window := range [][2]time.Time{
+		{at(13, 0), at(13, 9)}, {at(13, 9), at(13, 10)}, {at(13, 9), at(14, 10)}, {at(13, 10), at(14, 9)},
+		{at(13, 4), at(13, 5)}, {at(18, 0), at(19, 0)}, {at(20, 0), at(21, 0)}, {at(1, 0), at(31, 0)},
+	} {
+		expected, err := jsonDB.Overlapping(window[0], window[1])
+		assert.NoError(t, err)
+		actual, err := sqliteDB.Overlapping(window[0], window[1])
+		assert.NoError(t, err)
+		var expectedNames, actualNames []string
+		for _, i := range expected {
+			expectedNames = append(expectedNames, i.Annotation)
+		}
+		for _, i := range actual {
+			actualNames = append(actualNames, i.Annotation)
+		}
+		assert.Equal(t, expectedNames, actualNames, window)
+	}
+	overlapping, _ := sqliteDB.Overlapping(at(13, 9), at(14, 10))
+	assert.Len(t, overlapping, 2)
+	overlapping, _ = sqliteDB.Overlapping(at(20, 0), at(21, 0))
+	if assert.Len(t, overlapping, 1) {
+		assert.Equal(t, "running", overlapping[0].Annotation)
+	}
+
+	// sql selects the candidates, negations of text are checked in Go only
+	expr, err := parseFilterExpr(strings.Fields("proj:gott +docs 2022-01-14"), cal, testUDAs)
+	assert.NoError(t, err)
+	assert.True(t, expr.exact)
+	assert.Contains(t, expr.cond, "project GLOB ?")
+	assert.Contains(t, expr.cond, "tags.tag = ?")
+	assert.Contains(t, expr.cond, "begin_at >= ?")
+	expr, _ = parseFilterExpr(strings.Fields("proj:gott not writing"), cal, testUDAs)
+	assert.False(t, expr.exact)
+	assert.Equal(t, "(project GLOB ? OR project GLOB ?)", expr.cond)
+}
+
+func TestSqliteClose(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "db.sqlite")
+	db := NewDatabaseSqlite(filename, Calendar{Clock: SystemClock{}})
+	assert.NoError(t, db.Load())
+	kept := NewInterval([]string{"kept"})
+	assert.NoError(t, db.Append(kept))
+	assert.NoError(t, db.Save())
+
+	// without save the changes are discarded
+	db = NewDatabaseSqlite(filename, Calendar{Clock: SystemClock{}})
+	assert.NoError(t, db.Load())
+	assert.NoError(t, db.Append(NewInterval([]string{"discarded"})))
+	db.RemoveById(kept.ID)
+	assert.NoError(t, db.Close())
+
+	db = NewDatabaseSqlite(filename, Calendar{Clock: SystemClock{}})
+	assert.NoError(t, db.Load())
+	defer db.Close()
+	intervals, err := db.Filter([]string{KeyAll})
+	assert.NoError(t, err)
+	if assert.Len(t, intervals, 1) {
+		assert.Equal(t, "kept", intervals[0].Annotation)
+	}
+}
+
+func TestSqliteMigrateDurationOnly(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	// files written before the schema was versioned have user_version 0
+	for _, version := range []int{0, 1} {
+		filename := filepath.Join(t.TempDir(), "db.sqlite")
+		raw, err := sql.Open("sqlite3", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// version 1 stored duration only intervals at midnight UTC
+		midnight := time.Date(2022, 1, 14, 0, 0, 0, 0, time.UTC).UnixNano()
+		point := time.Date(2022, 1, 14, 15, 0, 0, 0, time.UTC)
+		_, err = raw.Exec(sqliteSchema + fmt.Sprintf("PRAGMA user_version = %d;", version))
+		assert.NoError(t, err)
+		_, err = raw.Exec("INSERT INTO intervals (id, begin_at, end_at, duration, annotation) VALUES ('cake', ?, ?, ?, 'bake a cake')",
+			midnight, midnight, int64(3*time.Hour))
+		assert.NoError(t, err)
+		// an interval ending when it began has no duration to anchor
+		_, err = raw.Exec("INSERT INTO intervals (id, begin_at, end_at, duration, annotation) VALUES ('point', ?, ?, 0, 'ring')",
+			point.UnixNano(), point.UnixNano())
+		assert.NoError(t, err)
+		assert.NoError(t, raw.Close())
+
+		cal := Calendar{Clock: FixedClock{time.Date(2022, 1, 20, 12, 0, 0, 0, newYork)}, DayBoundary: 4 * time.Hour}
+		db := NewDatabaseSqlite(filename, cal)
+		assert.NoError(t, db.Load())
+		assert.Len(t, db.Migrated(), 1, version)
+		intervals, err := db.Filter([]string{"2022-01-14", "bake"})
+		assert.NoError(t, err)
+		if assert.Len(t, intervals, 1, version) {
+			assert.True(t, cal.DayStart(time.Date(2022, 1, 14, 0, 0, 0, 0, newYork)).Equal(intervals[0].Begin), intervals[0].Begin)
+		}
+		if i, found := db.Get("point"); assert.True(t, found, version) {
+			assert.True(t, point.Equal(i.Begin), i.Begin)
+		}
+		assert.NoError(t, db.Save())
+
+		db = NewDatabaseSqlite(filename, cal)
+		assert.NoError(t, db.Load())
+		assert.Empty(t, db.Migrated())
+		db.Close()
+	}
+
+	// a new file needs no migration
+	db := NewDatabaseSqlite(filepath.Join(t.TempDir(), "db.sqlite"), Calendar{Clock: SystemClock{}})
+	assert.NoError(t, db.Load())
+	defer db.Close()
+	assert.Empty(t, db.Migrated())
+}
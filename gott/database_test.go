@@ -0,0 +1,70 @@
+package gott
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonSaveKeepsBackup(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "db.json")
+
+	db := NewDatabaseJson(filename, Calendar{Clock: SystemClock{}})
+	assert.NoError(t, db.Load())
+	db.Append(NewInterval([]string{"first"}))
+	assert.NoError(t, db.Save())
+
+	db.Append(NewInterval([]string{"second"}))
+	assert.NoError(t, db.Save())
+
+	var backup DatabaseJson
+	data, err := ioutil.ReadFile(backupName(filename, 1))
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &backup))
+	assert.Len(t, backup.Intervals, 1)
+
+	loaded := NewDatabaseJson(filename, Calendar{Clock: SystemClock{}})
+	assert.NoError(t, loaded.Load())
+	assert.Equal(t, 2, loaded.Count())
+
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(filename), "*.tmp"))
+	assert.Empty(t, matches)
+}
+
+func TestJsonSaveRotatesBackups(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "db.json")
+	db := NewDatabaseJson(filename, Calendar{Clock: SystemClock{}})
+	db.backups = 2
+	assert.NoError(t, db.Load())
+	for n := 1; n <= 4; n++ {
+		assert.NoError(t, db.Append(NewInterval([]string{"interval"})))
+		assert.NoError(t, db.Save())
+	}
+
+	// .bak.1 is the latest previous version, older ones are dropped
+	for n, count := range map[int]int{1: 3, 2: 2} {
+		var backup DatabaseJson
+		data, err := ioutil.ReadFile(backupName(filename, n))
+		assert.NoError(t, err)
+		assert.NoError(t, json.Unmarshal(data, &backup))
+		assert.Len(t, backup.Intervals, count, n)
+	}
+	assert.NoFileExists(t, backupName(filename, 3))
+
+	// without backups nothing is kept
+	filename = filepath.Join(t.TempDir(), "db.json")
+	db = NewDatabaseJson(filename, Calendar{Clock: SystemClock{}})
+	db.backups = 0
+	assert.NoError(t, db.Load())
+	assert.NoError(t, db.Save())
+	matches, _ := filepath.Glob(filename + backupSuffix + "*")
+	assert.Empty(t, matches)
+}
+
+func TestJsonSaveFails(t *testing.T) {
+	db := NewDatabaseJson(filepath.Join(t.TempDir(), "missing", "db.json"), Calendar{Clock: SystemClock{}})
+	assert.Error(t, db.Save())
+}
@@ -0,0 +1,249 @@
+// Package dateexpr resolves date expressions like :lastweek, monday, 3d ago
+// or 2022-W07 to the days they cover.
+//
+// All days are the midnight of a date in the location of the given today.
+// Mapping them to working days is up to the caller.
+package dateexpr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Today       = ":today"
+	Yesterday   = ":yesterday"
+	Week        = ":week"
+	LastWeek    = ":lastweek"
+	Month       = ":month"
+	LastMonth   = ":lastmonth"
+	Quarter     = ":quarter"
+	LastQuarter = ":lastquarter"
+	Year        = ":year"
+	LastYear    = ":lastyear"
+)
+
+// Keywords are the expressions starting with a colon.
+var Keywords = []string{Today, Yesterday, Week, LastWeek, Month, LastMonth, Quarter, LastQuarter, Year, LastYear}
+
+const (
+	dateFormat = "2006-01-02"
+	agoSuffix  = "ago"
+)
+
+// Range is the span of days from the day From to the day To, both included.
+type Range struct {
+	From time.Time
+	To   time.Time
+}
+
+// Single reports whether the range covers exactly one day.
+func (r Range) Single() bool {
+	return r.From.Equal(r.To)
+}
+
+func day(t time.Time) Range {
+	return Range{From: t, To: t}
+}
+
+var (
+	relativeRegexp = regexp.MustCompile(`^(\d+)([a-z]+)$`)
+	isoWeekRegexp  = regexp.MustCompile(`^(\d{4})-W(\d{2})$`)
+	isoDateRegexp  = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// parseWeekday parses the english name of a weekday or its first three
+// letters.
+func parseWeekday(name string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// Parse parses the date expression at the start of args relative to the day
+// today. n is the number of args it consists of, 0 if args do not start with a
+// date expression. err is set if they look like one but are invalid.
+//
+//	:today :yesterday                     the day
+//	:week :month :quarter :year           the start of the period until today
+//	:lastweek :lastmonth ...              the whole previous period
+//	today yesterday tomorrow              the day
+//	monday ... sunday, mon ... sun        the latest weekday, today included
+//	3d ago, 3 days ago, -3d               days, weeks (w), months (mo) or years (y) ago
+//	sod eod sow eow som eom soq eoq soy eoy  the start or end of the period
+//	2022-01-14                            the day
+//	2022-W07                              monday until sunday of the ISO week
+func Parse(args []string, today time.Time) (r Range, n int, err error) {
+	if len(args) == 0 {
+		return r, 0, nil
+	}
+	t := strings.ToLower(args[0])
+
+	if strings.HasPrefix(t, ":") {
+		r, err := parseKeyword(t, today)
+		if err != nil {
+			return r, 0, err
+		}
+		return r, 1, nil
+	}
+
+	switch t {
+	case "today", "sod", "eod":
+		return day(today), 1, nil
+	case "yesterday":
+		return day(today.AddDate(0, 0, -1)), 1, nil
+	case "tomorrow":
+		return day(today.AddDate(0, 0, 1)), 1, nil
+	case "sow":
+		return day(startOfWeek(today)), 1, nil
+	case "eow":
+		return day(startOfWeek(today).AddDate(0, 0, 6)), 1, nil
+	case "som":
+		return day(startOfMonth(today)), 1, nil
+	case "eom":
+		return day(startOfMonth(today).AddDate(0, 1, -1)), 1, nil
+	case "soq":
+		return day(startOfQuarter(today)), 1, nil
+	case "eoq":
+		return day(startOfQuarter(today).AddDate(0, 3, -1)), 1, nil
+	case "soy":
+		return day(startOfYear(today)), 1, nil
+	case "eoy":
+		return day(startOfYear(today).AddDate(1, 0, -1)), 1, nil
+	}
+
+	if weekday, found := parseWeekday(t); found {
+		offset := (int(today.Weekday()) - int(weekday) + 7) % 7
+		return day(today.AddDate(0, 0, -offset)), 1, nil
+	}
+
+	// -3d
+	if rel := strings.TrimPrefix(t, "-"); rel != t {
+		if m := relativeRegexp.FindStringSubmatch(rel); m != nil {
+			if r, ok := ago(m[1], m[2], today); ok {
+				return r, 1, nil
+			}
+		}
+	}
+
+	// 3d ago
+	if m := relativeRegexp.FindStringSubmatch(t); m != nil && len(args) > 1 && strings.EqualFold(args[1], agoSuffix) {
+		if r, ok := ago(m[1], m[2], today); ok {
+			return r, 2, nil
+		}
+		return r, 0, fmt.Errorf("invalid unit %s in %s %s", m[2], args[0], args[1])
+	}
+
+	// 3 days ago
+	if len(args) > 2 && strings.EqualFold(args[2], agoSuffix) {
+		if _, err := strconv.Atoi(t); err == nil {
+			if r, ok := ago(t, strings.ToLower(args[1]), today); ok {
+				return r, 3, nil
+			}
+			return r, 0, fmt.Errorf("invalid unit %s in %s", args[1], strings.Join(args[:3], " "))
+		}
+	}
+
+	if m := isoWeekRegexp.FindStringSubmatch(args[0]); m != nil {
+		r, err := isoWeek(m[1], m[2], today.Location())
+		if err != nil {
+			return r, 0, err
+		}
+		return r, 1, nil
+	}
+
+	if isoDateRegexp.MatchString(t) {
+		d, err := time.ParseInLocation(dateFormat, t, today.Location())
+		if err != nil {
+			return r, 0, fmt.Errorf("invalid date %s: %s", args[0], err.Error())
+		}
+		return day(d), 1, nil
+	}
+
+	return r, 0, nil
+}
+
+func parseKeyword(keyword string, today time.Time) (Range, error) {
+	switch keyword {
+	case Today:
+		return day(today), nil
+	case Yesterday:
+		return day(today.AddDate(0, 0, -1)), nil
+	case Week:
+		return Range{From: startOfWeek(today), To: today}, nil
+	case LastWeek:
+		from := startOfWeek(today).AddDate(0, 0, -7)
+		return Range{From: from, To: from.AddDate(0, 0, 6)}, nil
+	case Month:
+		return Range{From: startOfMonth(today), To: today}, nil
+	case LastMonth:
+		from := startOfMonth(today).AddDate(0, -1, 0)
+		return Range{From: from, To: from.AddDate(0, 1, -1)}, nil
+	case Quarter:
+		return Range{From: startOfQuarter(today), To: today}, nil
+	case LastQuarter:
+		from := startOfQuarter(today).AddDate(0, -3, 0)
+		return Range{From: from, To: from.AddDate(0, 3, -1)}, nil
+	case Year:
+		return Range{From: startOfYear(today), To: today}, nil
+	case LastYear:
+		from := startOfYear(today).AddDate(-1, 0, 0)
+		return Range{From: from, To: from.AddDate(1, 0, -1)}, nil
+	}
+	return Range{}, fmt.Errorf("unknown keyword %s. Choose one of %s", keyword, strings.Join(Keywords, ", "))
+}
+
+// ago returns the day count units before today.
+func ago(count, unit string, today time.Time) (Range, bool) {
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return Range{}, false
+	}
+	switch unit {
+	case "d", "day", "days":
+		return day(today.AddDate(0, 0, -n)), true
+	case "w", "wk", "wks", "week", "weeks":
+		return day(today.AddDate(0, 0, -7*n)), true
+	case "mo", "mon", "mons", "month", "months":
+		return day(today.AddDate(0, -n, 0)), true
+	case "y", "yr", "yrs", "year", "years":
+		return day(today.AddDate(-n, 0, 0)), true
+	}
+	return Range{}, false
+}
+
+// isoWeek returns monday until sunday of the ISO week.
+func isoWeek(year, week string, loc *time.Location) (Range, error) {
+	y, _ := strconv.Atoi(year)
+	w, _ := strconv.Atoi(week)
+	// january 4th is always in the first week
+	monday := startOfWeek(time.Date(y, 1, 4, 0, 0, 0, 0, loc)).AddDate(0, 0, 7*(w-1))
+	if iy, iw := monday.ISOWeek(); w < 1 || iy != y || iw != w {
+		return Range{}, fmt.Errorf("invalid week %s-W%s", year, week)
+	}
+	return Range{From: monday, To: monday.AddDate(0, 0, 6)}, nil
+}
+
+func startOfWeek(t time.Time) time.Time {
+	return t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+}
+
+func startOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+func startOfQuarter(t time.Time) time.Time {
+	m := (t.Month()-1)/3*3 + 1
+	return time.Date(t.Year(), m, 1, 0, 0, 0, 0, t.Location())
+}
+
+func startOfYear(t time.Time) time.Time {
+	return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,89 @@
+package dateexpr
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	// wednesday
+	today := date(2022, 2, 16)
+
+	tests := []struct {
+		expr string
+		from time.Time
+		to   time.Time
+	}{
+		{":today", date(2022, 2, 16), date(2022, 2, 16)},
+		{":yesterday", date(2022, 2, 15), date(2022, 2, 15)},
+		{":week", date(2022, 2, 14), date(2022, 2, 16)},
+		{":lastweek", date(2022, 2, 7), date(2022, 2, 13)},
+		{":month", date(2022, 2, 1), date(2022, 2, 16)},
+		{":lastmonth", date(2022, 1, 1), date(2022, 1, 31)},
+		{":quarter", date(2022, 1, 1), date(2022, 2, 16)},
+		{":lastquarter", date(2021, 10, 1), date(2021, 12, 31)},
+		{":year", date(2022, 1, 1), date(2022, 2, 16)},
+		{":lastyear", date(2021, 1, 1), date(2021, 12, 31)},
+		{"today", date(2022, 2, 16), date(2022, 2, 16)},
+		{"Yesterday", date(2022, 2, 15), date(2022, 2, 15)},
+		{"tomorrow", date(2022, 2, 17), date(2022, 2, 17)},
+		{"monday", date(2022, 2, 14), date(2022, 2, 14)},
+		{"wed", date(2022, 2, 16), date(2022, 2, 16)},
+		{"thursday", date(2022, 2, 10), date(2022, 2, 10)},
+		{"3d ago", date(2022, 2, 13), date(2022, 2, 13)},
+		{"2 weeks ago", date(2022, 2, 2), date(2022, 2, 2)},
+		{"-2w", date(2022, 2, 2), date(2022, 2, 2)},
+		{"-1mo", date(2022, 1, 16), date(2022, 1, 16)},
+		{"-1y", date(2021, 2, 16), date(2021, 2, 16)},
+		{"sow", date(2022, 2, 14), date(2022, 2, 14)},
+		{"eow", date(2022, 2, 20), date(2022, 2, 20)},
+		{"som", date(2022, 2, 1), date(2022, 2, 1)},
+		{"eom", date(2022, 2, 28), date(2022, 2, 28)},
+		{"eoq", date(2022, 3, 31), date(2022, 3, 31)},
+		{"eoy", date(2022, 12, 31), date(2022, 12, 31)},
+		{"2022-01-14", date(2022, 1, 14), date(2022, 1, 14)},
+		{"2022-W07", date(2022, 2, 14), date(2022, 2, 20)},
+		{"2021-W01", date(2021, 1, 4), date(2021, 1, 10)},
+		{"2020-W53", date(2020, 12, 28), date(2021, 1, 3)},
+	}
+	for _, test := range tests {
+		args := strings.Fields(test.expr)
+		r, n, err := Parse(append(args, "rest"), today)
+		assert.NoError(t, err, test.expr)
+		assert.Equal(t, len(args), n, test.expr)
+		assert.Equal(t, test.from, r.From, test.expr)
+		assert.Equal(t, test.to, r.To, test.expr)
+	}
+}
+
+func TestParseNoDate(t *testing.T) {
+	today := date(2022, 2, 16)
+	for _, expr := range []string{"", "meeting", "+tag", "-tag", "3d", "proj:gott", "2022"} {
+		_, n, err := Parse(strings.Fields(expr), today)
+		assert.NoError(t, err, expr)
+		assert.Equal(t, 0, n, expr)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	today := date(2022, 2, 16)
+	for _, expr := range []string{":tomorrow", "2022-13-01", "2022-02-30", "2021-W53", "2022-W00", "3x ago", "3 lightyears ago"} {
+		_, _, err := Parse(strings.Fields(expr), today)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestRangeSingle(t *testing.T) {
+	today := date(2022, 2, 16)
+	r, _, _ := Parse([]string{":today"}, today)
+	assert.True(t, r.Single())
+	r, _, _ = Parse([]string{":week"}, today)
+	assert.False(t, r.Single())
+}
@@ -0,0 +1,295 @@
+package gott
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	ProblemDuplicate      = "duplicate"
+	ProblemEndBeforeBegin = "end-before-begin"
+	ProblemStatus         = "status"
+	ProblemCurrent        = "current"
+	ProblemOverlap        = "overlap"
+)
+
+const (
+	// OverlapTrim ends the earlier interval where the later one begins
+	OverlapTrim = "trim"
+	// OverlapMerge extends the earlier interval over the later one and
+	// removes the later one
+	OverlapMerge = "merge"
+)
+
+var OverlapStrategies = []string{OverlapTrim, OverlapMerge}
+
+// Problem is an inconsistency of the database found by diagnose.
+type Problem struct {
+	Kind        string
+	IDs         []string
+	Description string
+	// Fix describes the fix, it is empty if the problem can not be fixed
+	Fix string
+	fix func() error
+}
+
+// diagnose checks the database for duplicate ids, intervals ending before
+// they begin, status disagreeing with the end, a current id not pointing to
+// the running interval and overlapping intervals. Overlaps are fixed with the
+// overlap strategy. The fixes are meant to be applied in order, each of them
+// checks if it is still needed.
+func (a *App) diagnose(overlap string) ([]Problem, error) {
+	db := a.Database
+	now := a.clock().Now()
+	intervals, err := db.Filter([]string{KeyAll})
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	var valid []*Interval
+	seen := map[string]bool{}
+	for _, i := range intervals {
+		id := i.ID
+		if seen[id] {
+			problems = append(problems, Problem{
+				Kind:        ProblemDuplicate,
+				IDs:         []string{id},
+				Description: fmt.Sprintf("%s uses the id of another interval", a.fmtInterval(i)),
+				Fix:         "drop duplicates",
+				fix:         func() error { return dropDuplicates(db, id) },
+			})
+			continue
+		}
+		seen[id] = true
+
+		if !i.End.IsZero() && i.End.Before(i.Begin) {
+			problems = append(problems, Problem{
+				Kind:        ProblemEndBeforeBegin,
+				IDs:         []string{id},
+				Description: fmt.Sprintf("%s ends before it begins", a.fmtInterval(i)),
+				Fix:         "swap begin and end",
+				fix:         func() error { return swapBeginEnd(db, id) },
+			})
+			continue
+		}
+		valid = append(valid, i)
+
+		if status := statusOf(i); i.Status != status {
+			problems = append(problems, Problem{
+				Kind:        ProblemStatus,
+				IDs:         []string{id},
+				Description: fmt.Sprintf("%s has status %q, but is %s", a.fmtInterval(i), i.Status, status),
+				Fix:         "set status " + status,
+				fix:         func() error { return setStatus(db, id) },
+			})
+		}
+	}
+
+	if p, found := a.diagnoseCurrent(valid); found {
+		problems = append(problems, p)
+	}
+	problems = append(problems, a.diagnoseOverlaps(valid, now, overlap)...)
+	return problems, nil
+}
+
+// statusOf returns the status the interval should have.
+func statusOf(i *Interval) string {
+	if i.End.IsZero() {
+		return StatusStarted
+	}
+	return StatusEnded
+}
+
+// diagnoseCurrent checks the current id points to the only running interval.
+func (a *App) diagnoseCurrent(intervals []*Interval) (Problem, bool) {
+	db := a.Database
+	current := db.CurrentID()
+	var running []*Interval
+	for _, i := range intervals {
+		if i.End.IsZero() {
+			running = append(running, i)
+		}
+	}
+
+	p := Problem{Kind: ProblemCurrent, Fix: "repair current", fix: func() error { return repairCurrent(db) }}
+	if current != "" {
+		p.IDs = append(p.IDs, current)
+	}
+	cur, found := db.Get(current)
+	switch {
+	case current != "" && !found:
+		p.Description = fmt.Sprintf("current interval %s does not exist", current)
+	case found && !cur.End.IsZero():
+		p.Description = fmt.Sprintf("current interval %s is stopped", a.fmtInterval(cur))
+	case len(running) > 1 || (len(running) == 1 && running[0].ID != current):
+		p.Description = fmt.Sprintf("%d intervals are running, but only the current one should", len(running))
+	default:
+		return p, false
+	}
+	for _, i := range running {
+		if i.ID != current {
+			p.IDs = append(p.IDs, i.ID)
+		}
+	}
+	return p, true
+}
+
+// diagnoseOverlaps checks the intervals sorted by begin for overlaps.
+func (a *App) diagnoseOverlaps(intervals []*Interval, now time.Time, overlap string) []Problem {
+	db := a.Database
+	var problems []Problem
+	for n, first := range intervals {
+		_, firstEnd, ok := span(first, now)
+		if !ok {
+			continue
+		}
+		for _, second := range intervals[n+1:] {
+			if !second.Begin.Before(firstEnd) {
+				break
+			}
+			if !overlaps(first, second, now) {
+				continue
+			}
+			p := Problem{
+				Kind:        ProblemOverlap,
+				IDs:         []string{first.ID, second.ID},
+				Description: fmt.Sprintf("%s overlaps %s", a.fmtInterval(first), a.fmtInterval(second)),
+			}
+			// running intervals are left to the user
+			if !first.End.IsZero() && !second.End.IsZero() {
+				firstID, secondID := first.ID, second.ID
+				switch overlap {
+				case OverlapTrim:
+					p.Fix = OverlapTrim
+					p.fix = func() error { return trimOverlap(db, firstID, secondID, now) }
+				case OverlapMerge:
+					p.Fix = OverlapMerge
+					p.fix = func() error { return mergeOverlap(db, firstID, secondID, now) }
+				}
+			}
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
+// Apply fixes the problem. It does nothing if the problem can not be fixed.
+func (p Problem) Apply() error {
+	if p.fix == nil {
+		return nil
+	}
+	return p.fix()
+}
+
+// dropDuplicates keeps the first interval with the id.
+func dropDuplicates(db Database, id string) error {
+	first, found := db.Get(id)
+	if !found {
+		return nil
+	}
+	kept := copyInterval(first)
+	db.RemoveById(id)
+	return db.AppendPtr(kept)
+}
+
+func swapBeginEnd(db Database, id string) error {
+	i, found := db.Get(id)
+	if !found || i.End.IsZero() || !i.End.Before(i.Begin) {
+		return nil
+	}
+	swapped := copyInterval(i)
+	swapped.Begin, swapped.End = i.End, i.Begin
+	swapped.Status = StatusEnded
+	return db.Apply(*swapped)
+}
+
+func setStatus(db Database, id string) error {
+	i, found := db.Get(id)
+	if !found || i.Status == statusOf(i) {
+		return nil
+	}
+	fixed := copyInterval(i)
+	fixed.Status = statusOf(i)
+	return db.Apply(*fixed)
+}
+
+// repairCurrent points the current id to the latest running interval. Other
+// running intervals are stopped when the next interval begins.
+func repairCurrent(db Database) error {
+	intervals, err := db.Filter([]string{KeyAll})
+	if err != nil {
+		return err
+	}
+	var running []*Interval
+	for _, i := range intervals {
+		if i.End.IsZero() {
+			running = append(running, i)
+		}
+	}
+	if len(running) == 0 {
+		return db.SetCurrent("")
+	}
+	sort.SliceStable(running, func(i, j int) bool {
+		return running[i].Begin.Before(running[j].Begin)
+	})
+	latest := running[len(running)-1]
+	for _, r := range running[:len(running)-1] {
+		for _, next := range intervals {
+			if next.Begin.After(r.Begin) {
+				stopped := copyInterval(r)
+				stopped.Stop(next.Begin)
+				if err := db.Apply(*stopped); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return db.SetCurrent(latest.ID)
+}
+
+// trimOverlap ends the first interval where the second begins. If the first
+// would become empty, the second begins where the first ends instead.
+// Intervals contained in others can not be trimmed.
+func trimOverlap(db Database, firstID, secondID string, now time.Time) error {
+	first, found := db.Get(firstID)
+	second, found2 := db.Get(secondID)
+	if !found || !found2 || !overlaps(first, second, now) {
+		return nil
+	}
+	if first.Begin.Before(second.Begin) {
+		trimmed := copyInterval(first)
+		trimmed.End = second.Begin
+		return db.Apply(*trimmed)
+	}
+	if second.End.After(first.End) {
+		trimmed := copyInterval(second)
+		trimmed.Begin = first.End
+		return db.Apply(*trimmed)
+	}
+	return fmt.Errorf("%s is contained in %s. edit it by hand", secondID, firstID)
+}
+
+// mergeOverlap extends the first interval over the second and removes the
+// second. The annotation, project and tags of the first are kept.
+func mergeOverlap(db Database, firstID, secondID string, now time.Time) error {
+	first, found := db.Get(firstID)
+	second, found2 := db.Get(secondID)
+	if !found || !found2 || !overlaps(first, second, now) {
+		return nil
+	}
+	merged := copyInterval(first)
+	if second.Begin.Before(merged.Begin) {
+		merged.Begin = second.Begin
+	}
+	if second.End.After(merged.End) {
+		merged.End = second.End
+	}
+	if err := db.Apply(*merged); err != nil {
+		return err
+	}
+	db.RemoveById(secondID)
+	return nil
+}
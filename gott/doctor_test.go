@@ -0,0 +1,83 @@
+package gott
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoctor(t *testing.T) {
+	for _, overlap := range OverlapStrategies {
+		app, _ := newTestApp(t)
+		app.Clock = FixedClock{Time: time.Date(2022, 1, 14, 18, 0, 0, 0, time.Local)}
+		assert.NoError(t, app.Open(false))
+		db := app.Journal.Database.(*DatabaseJson)
+
+		at := func(hour, minute int) time.Time {
+			return time.Date(2022, 1, 14, hour, minute, 0, 0, time.Local)
+		}
+		add := func(id string, begin, end time.Time, status string) {
+			db.Intervals = append(db.Intervals, &Interval{ID: id, Begin: begin, End: end, Status: status})
+		}
+		add("meeting", at(9, 0), at(10, 0), StatusEnded)
+		add("meeting", at(9, 0), at(10, 0), StatusEnded)
+		add("call", at(9, 30), at(10, 30), StatusEnded)
+		add("swapped", at(12, 0), at(11, 0), StatusEnded)
+		add("status", at(13, 0), at(14, 0), StatusStarted)
+		add("forgotten", at(15, 0), time.Time{}, StatusStarted)
+		add("running", at(16, 0), time.Time{}, StatusStarted)
+		db.Current = "missing"
+
+		problems, err := app.diagnose(overlap)
+		assert.NoError(t, err)
+		var kinds []string
+		for _, p := range problems {
+			kinds = append(kinds, p.Kind)
+		}
+		assert.Equal(t, []string{ProblemDuplicate, ProblemEndBeforeBegin, ProblemStatus, ProblemCurrent, ProblemOverlap, ProblemOverlap}, kinds)
+
+		for _, p := range problems {
+			assert.NoError(t, p.Apply())
+		}
+		remaining, err := app.diagnose(overlap)
+		assert.NoError(t, err)
+		assert.Empty(t, remaining, overlap)
+
+		meeting, _ := db.Get("meeting")
+		switch overlap {
+		case OverlapTrim:
+			assert.Equal(t, at(9, 30), meeting.End)
+			assert.Equal(t, 6, db.Count())
+		case OverlapMerge:
+			assert.Equal(t, at(10, 30), meeting.End)
+			assert.Equal(t, 5, db.Count())
+		}
+		swapped, _ := db.Get("swapped")
+		assert.Equal(t, at(11, 0), swapped.Begin)
+		forgotten, _ := db.Get("forgotten")
+		assert.Equal(t, at(16, 0), forgotten.End)
+		assert.Equal(t, "running", db.CurrentID())
+		assert.NoError(t, app.Close())
+	}
+}
+
+func TestAppendValidates(t *testing.T) {
+	db := NewDatabaseJson("", Calendar{Clock: SystemClock{}})
+	now := time.Now()
+
+	i := NewInterval([]string{"backwards"})
+	i.Begin = now
+	i.End = now.Add(-time.Hour)
+	assert.Error(t, db.Append(i))
+
+	i = NewInterval([]string{"ok"})
+	i.Begin = now
+	i.End = now.Add(time.Hour)
+	i.Status = StatusEnded
+	assert.NoError(t, db.Append(i))
+	assert.Error(t, db.Append(i), "duplicate id")
+
+	i.Status = StatusStarted
+	assert.Error(t, db.Apply(i))
+}
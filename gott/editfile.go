@@ -0,0 +1,477 @@
+package gott
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	uuid "github.com/nu7hatch/gouuid"
+)
+
+// The edit file has one interval per line:
+//
+//	ID  DATE  BEGIN  END  DURATION  "annotation" proj:NAME +TAG ref:REF uda:KEY=JSON
+//
+// Empty columns are written as -. BEGIN and END are HH:MM on the working day
+// DATE, with seconds if needed, or a full date and time. An END before the
+// BEGIN is on the next day. Values containing spaces or quotes are quoted
+// like Go strings, e.g. +"tag with spaces". Unquoted words are annotation
+// text, too.
+const (
+	editEmpty     = "-"
+	editUDAPrefix = "uda:"
+	editUDASep    = "="
+
+	// editErrorPrefix marks errors written into the edit file
+	editErrorPrefix = "# ERROR: "
+
+	editTimeFormat     = "15:04:05.999999999"
+	editDateTimeFormat = "2006-01-02T15:04:05.999999999"
+)
+
+func writeEditFile(w io.Writer, intervals []*Interval, filterArgs []string, cal Calendar) {
+	now := cal.Now()
+
+	fmt.Fprintln(w, "# Edit below values to change tracking data")
+	fmt.Fprintln(w, "# - delete rows to delete")
+	fmt.Fprintln(w, "# - set BEGIN and END to - to just set the DURATION")
+	fmt.Fprintln(w, "# - set END to - for a running interval")
+	fmt.Fprintln(w, "# - quote values with spaces like \"some text\" or +\"some tag\"")
+	fmt.Fprintln(w, "# ID  DATE  BEGIN  END  DURATION  \"ANNOTATION\" proj:PROJECT +TAG ref:REF uda:KEY=JSON")
+	fmt.Fprintln(w)
+
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, i := range intervals {
+		fmt.Fprintln(writer, formatEditLine(i, cal))
+	}
+	writer.Flush()
+
+	fmt.Fprint(w, "\n\n# NEW ENTRIES HERE #############################################\n")
+	fmt.Fprintf(w, "# - %s 09:00 10:00 - \"annotation\" proj:project +tag\n", now.Format(dateFormat))
+	fmt.Fprintf(w, "# - %s - - 1h \"annotation\"\n", now.Format(dateFormat))
+
+	fmt.Fprint(w, "\n\n\n\n# meta #########################################################\n")
+	fmt.Fprintf(w, "# ;; filter == %s\n", strings.Join(filterArgs, " "))
+}
+
+// formatEditLine formats the interval as line of the edit file with tabs
+// between the columns.
+func formatEditLine(i *Interval, cal Calendar) string {
+	loc := cal.Now().Location()
+	begin := i.Begin.In(loc)
+	day := cal.Day(begin)
+
+	beginText, endText := editEmpty, editEmpty
+	switch {
+	case i.End.Equal(i.Begin) && i.Begin.Equal(cal.DayStart(day)):
+		// duration only
+	case i.End.Equal(i.Begin):
+		beginText = formatEditTime(begin, day, begin, cal)
+		endText = beginText
+	default:
+		beginText = formatEditTime(begin, day, time.Time{}, cal)
+		if !i.End.IsZero() {
+			endText = formatEditTime(i.End.In(loc), day, begin, cal)
+		}
+	}
+	durationText := editEmpty
+	if i.Duration != 0 {
+		durationText = i.Duration.String()
+	}
+
+	var attrs []string
+	if i.Annotation != "" {
+		attrs = append(attrs, strconv.Quote(i.Annotation))
+	}
+	if i.Project != "" {
+		attrs = append(attrs, ProjectPrefixShort+quoteEditValue(i.Project))
+	}
+	for _, tag := range i.Tags {
+		attrs = append(attrs, TagPrefix+quoteEditValue(tag))
+	}
+	if i.Ref != "" {
+		attrs = append(attrs, RefPrefix+quoteEditValue(i.Ref))
+	}
+	var keys []string
+	for key := range i.UDA {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, _ := json.Marshal(i.UDA[key])
+		attrs = append(attrs, editUDAPrefix+quoteEditValue(key)+editUDASep+string(value))
+	}
+
+	columns := []string{i.ID, day.Format(dateFormat), beginText, endText, durationText}
+	if len(attrs) > 0 {
+		columns = append(columns, strings.Join(attrs, " "))
+	}
+	return strings.Join(columns, "\t")
+}
+
+// formatEditTime formats t as short as possible, so parseEditTime returns t
+// again.
+func formatEditTime(t, day, begin time.Time, cal Calendar) string {
+	short := t.Format(timeFormat)
+	if t.Second() != 0 || t.Nanosecond() != 0 {
+		short = t.Format(editTimeFormat)
+	}
+	if parsed, err := parseEditTime(short, day, begin, cal); err == nil && parsed.Equal(t) {
+		return short
+	}
+	return t.Format(editDateTimeFormat)
+}
+
+// parseEditTime parses a time of the working day or a full date and time. If
+// begin is set, times before it are on the next day.
+func parseEditTime(value string, day, begin time.Time, cal Calendar) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02T" + timeFormat, editDateTimeFormat} {
+		if t, err := time.ParseInLocation(layout, value, day.Location()); err == nil {
+			return t, nil
+		}
+	}
+	t, err := time.Parse(timeFormat, value)
+	if err != nil {
+		t, err = time.Parse(editTimeFormat, value)
+	}
+	if err != nil {
+		return t, fmt.Errorf("invalid time %s. use HH:MM[:SS] or YYYY-MM-DDTHH:MM[:SS]", value)
+	}
+	clock := time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	result := cal.At(day, t.Hour(), t.Minute()).Add(clock)
+	if !begin.IsZero() && result.Before(begin) {
+		result = cal.At(startOfDay(result).AddDate(0, 0, 1), t.Hour(), t.Minute()).Add(clock)
+	}
+	return result, nil
+}
+
+// quoteEditValue quotes values which would not be read back as one value.
+// Values containing = are quoted to tell uda keys from values.
+func quoteEditValue(value string) string {
+	if value == "" || strings.ContainsAny(value, `"=`) || strings.IndexFunc(value, func(r rune) bool {
+		return unicode.IsSpace(r) || !unicode.IsPrint(r)
+	}) >= 0 {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// unquoteEditValue reads a value written by quoteEditValue.
+func unquoteEditValue(value string) (string, error) {
+	if strings.HasPrefix(value, `"`) {
+		return strconv.Unquote(value)
+	}
+	return value, nil
+}
+
+// splitEditLine splits the line at spaces outside of quotes.
+func splitEditLine(line string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inField, quoted, escaped := false, false, false
+	for _, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && unicode.IsSpace(r):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+			continue
+		}
+		inField = true
+		field.WriteRune(r)
+	}
+	if quoted {
+		return nil, errors.New("missing closing quote")
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// parseEditLine parses a line of the edit file. Dates are working days of cal
+// in its location. New intervals have an empty ID.
+func parseEditLine(line string, cal Calendar) (Interval, error) {
+	var interval Interval
+	fields, err := splitEditLine(line)
+	if err != nil {
+		return interval, err
+	}
+	if len(fields) < 5 {
+		return interval, fmt.Errorf("expected ID DATE BEGIN END DURATION, got %d columns", len(fields))
+	}
+	id, date, begin, end, duration := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	if id != editEmpty {
+		interval.ID = id
+	}
+
+	day, err := time.ParseInLocation(dateFormat, date, cal.Now().Location())
+	if err != nil {
+		return interval, fmt.Errorf("error parsing date '%s'. use the format YYYY-MM-DD", date)
+	}
+
+	if duration != editEmpty {
+		if interval.Duration, err = time.ParseDuration(duration); err != nil {
+			return interval, fmt.Errorf("error parsing duration '%s': %s", duration, err.Error())
+		}
+	}
+
+	switch {
+	case begin == editEmpty && end == editEmpty:
+		interval.Begin = cal.DayStart(day)
+		interval.End = interval.Begin
+	case begin == editEmpty:
+		return interval, errors.New("END is set, but BEGIN is empty")
+	default:
+		if interval.Begin, err = parseEditTime(begin, day, time.Time{}, cal); err != nil {
+			return interval, err
+		}
+		if end != editEmpty {
+			if interval.End, err = parseEditTime(end, day, interval.Begin, cal); err != nil {
+				return interval, err
+			}
+		}
+	}
+	interval.Status = statusOf(&interval)
+
+	var words []string
+	for _, field := range fields[5:] {
+		if strings.HasPrefix(field, `"`) {
+			text, err := strconv.Unquote(field)
+			if err != nil {
+				return interval, fmt.Errorf("invalid quoted text %s", field)
+			}
+			words = append(words, text)
+			continue
+		}
+		if prefix, isAttr := editAttrPrefix(field); isAttr {
+			if err := parseEditAttr(prefix, field[len(prefix):], &interval); err != nil {
+				return interval, err
+			}
+			continue
+		}
+		words = append(words, field)
+	}
+	interval.Annotation = strings.Join(words, " ")
+	interval.Raw = formatRaw(&interval)
+	return interval, nil
+}
+
+// editAttrPrefix returns the prefix of fields setting an attribute instead of
+// annotation text.
+func editAttrPrefix(field string) (string, bool) {
+	for _, prefix := range []string{ProjectPrefixShort, ProjectPrefix, RefPrefix, editUDAPrefix} {
+		if strings.HasPrefix(field, prefix) {
+			return prefix, true
+		}
+	}
+	if strings.HasPrefix(field, TagPrefix) && field != TagPrefix {
+		return TagPrefix, true
+	}
+	return "", false
+}
+
+func parseEditAttr(prefix, rest string, interval *Interval) error {
+	if prefix == editUDAPrefix {
+		key, value, err := parseEditUDA(rest)
+		if err != nil {
+			return fmt.Errorf("invalid %s%s, use %sKEY=JSON: %s", prefix, rest, editUDAPrefix, err.Error())
+		}
+		if interval.UDA == nil {
+			interval.UDA = make(map[string]interface{})
+		}
+		interval.UDA[key] = value
+		return nil
+	}
+
+	value, err := unquoteEditValue(rest)
+	if err != nil {
+		return fmt.Errorf("invalid quoted value %s%s", prefix, rest)
+	}
+	switch prefix {
+	case ProjectPrefixShort, ProjectPrefix:
+		interval.Project = value
+	case RefPrefix:
+		interval.Ref = value
+	case TagPrefix:
+		interval.Tags = append(interval.Tags, value)
+	}
+	return nil
+}
+
+// parseEditUDA parses KEY=JSON. Keys containing = are quoted.
+func parseEditUDA(uda string) (string, interface{}, error) {
+	key := uda
+	if strings.HasPrefix(uda, `"`) {
+		quoted, err := strconv.QuotedPrefix(uda)
+		if err != nil {
+			return "", nil, err
+		}
+		key = quoted
+	} else if sep := strings.Index(uda, editUDASep); sep >= 0 {
+		key = uda[:sep]
+	}
+	if !strings.HasPrefix(uda[len(key):], editUDASep) {
+		return "", nil, errors.New("missing " + editUDASep)
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(uda[len(key)+len(editUDASep):]), &value); err != nil {
+		return "", nil, err
+	}
+	key, err := unquoteEditValue(key)
+	return key, value, err
+}
+
+// editLineError is an error in a line of the edit file.
+type editLineError struct {
+	Line int
+	Err  error
+}
+
+func (e *editLineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err.Error())
+}
+
+// parseEditFile parses and validates the intervals of the edit file, the
+// declared udas included. Errors are of type *editLineError.
+func parseEditFile(r io.Reader, cal Calendar, udas UDAs) ([]Interval, error) {
+	scanner := bufio.NewScanner(r)
+	var result []Interval
+	seen := map[string]bool{}
+	running := 0
+	for line := 1; scanner.Scan(); line++ {
+		t := strings.TrimSpace(scanner.Text())
+		// ignore comments and empty lines
+		if t == "" || strings.HasPrefix(t, "#") {
+			continue
+		}
+		i, err := parseEditLine(t, cal)
+		if err == nil {
+			// new intervals get their id later
+			valid := i
+			if valid.ID == "" {
+				valid.ID = "(new)"
+			}
+			err = valid.Validate()
+			if err == nil {
+				err = udas.Validate(&valid)
+			}
+		}
+		if err == nil && i.ID != "" && seen[i.ID] {
+			err = fmt.Errorf("id %s is used in another line", i.ID)
+		}
+		if err == nil && i.End.IsZero() && running > 0 {
+			err = fmt.Errorf("the interval in line %d is running already. only one can run", running)
+		}
+		if err != nil {
+			return nil, &editLineError{Line: line, Err: err}
+		}
+		if i.End.IsZero() {
+			running = line
+		}
+		seen[i.ID] = true
+		result = append(result, i)
+	}
+	return result, scanner.Err()
+}
+
+// annotateEditFile writes the error above its line into the edit file and
+// removes the errors of earlier attempts. A nil error only removes them.
+func annotateEditFile(content []byte, lineErr *editLineError) []byte {
+	var buf bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if lineErr != nil && line == lineErr.Line {
+			fmt.Fprintf(&buf, "%s%s\n", editErrorPrefix, lineErr.Err.Error())
+		}
+		if strings.HasPrefix(text, editErrorPrefix) {
+			continue
+		}
+		fmt.Fprintln(&buf, text)
+	}
+	return buf.Bytes()
+}
+
+// editChanges are the changes made in the edit file.
+type editChanges struct {
+	Added   []Interval
+	Changed []Interval
+	Deleted []*Interval
+}
+
+// Count returns the number of changed intervals.
+func (c editChanges) Count() int {
+	return len(c.Added) + len(c.Changed) + len(c.Deleted)
+}
+
+// diffEdit compares the intervals written to the edit file with the edited
+// ones. Edited intervals without id are added with a new one.
+func (a *App) diffEdit(before []*Interval, after []Interval) editChanges {
+	var c editChanges
+	kept := map[string]bool{}
+	for _, i := range after {
+		if i.ID == "" {
+			id, _ := uuid.NewV4()
+			i.ID = id.String()
+		}
+		kept[i.ID] = true
+		old := findInterval(before, i.ID)
+		switch {
+		case old == nil:
+			c.Added = append(c.Added, i)
+		case len(a.describeChanges(old, &i)) > 0:
+			c.Changed = append(c.Changed, i)
+		}
+	}
+	for _, i := range before {
+		if !kept[i.ID] {
+			c.Deleted = append(c.Deleted, i)
+		}
+	}
+	return c
+}
+
+func findInterval(intervals []*Interval, id string) *Interval {
+	for _, i := range intervals {
+		if i.ID == id {
+			return i
+		}
+	}
+	return nil
+}
+
+// printEditChanges lists the changes with the handles of ids.
+func (a *App) printEditChanges(before []*Interval, c editChanges, ids map[string]string) {
+	for _, i := range c.Added {
+		fmt.Fprintf(a.Out, "add %s\n", a.fmtInterval(&i))
+	}
+	for _, i := range c.Changed {
+		old := findInterval(before, i.ID)
+		fmt.Fprintf(a.Out, "change %s %s\n", ids[i.ID], a.fmtInterval(old))
+		for _, change := range a.describeChanges(old, &i) {
+			fmt.Fprintf(a.Out, "  %s\n", change)
+		}
+	}
+	for _, i := range c.Deleted {
+		fmt.Fprintf(a.Out, "delete %s %s\n", ids[i.ID], a.fmtInterval(i))
+	}
+}
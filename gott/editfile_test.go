@@ -0,0 +1,177 @@
+package gott
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditFileRoundTrip(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	cal := Calendar{Clock: FixedClock{Time: time.Date(2022, 1, 14, 12, 0, 0, 0, loc)}, DayBoundary: 4 * time.Hour}
+	at := func(day, hour, minute, second, nsec int) time.Time {
+		return time.Date(2022, 1, day, hour, minute, second, nsec, loc)
+	}
+
+	annotations := []string{
+		"",
+		"plain words",
+		"a.b/c, d+e",
+		`say "hi" \o/`,
+		"tab\tand\nnewline",
+		"ümlaut ✓ 日本語",
+		"proj:not-a-project +not-a-tag",
+		"  leading and trailing  ",
+		"-",
+		`"`,
+		"uda:x=1",
+	}
+	var intervals []*Interval
+	for n, annotation := range annotations {
+		intervals = append(intervals, &Interval{
+			ID:         fmt.Sprintf("id-%d", n),
+			Begin:      at(10, 9+n%8, n, 0, 0),
+			End:        at(10, 10+n%8, 0, 0, 0),
+			Annotation: annotation,
+		})
+	}
+	intervals = append(intervals,
+		&Interval{
+			ID:      "attrs",
+			Begin:   at(11, 9, 0, 0, 0),
+			End:     at(11, 10, 0, 0, 0),
+			Project: "gott.docs",
+			Tags:    []string{"docs", "with space", "ü+ß", ""},
+			Ref:     "https://example.com/a?b=c",
+			UDA: map[string]interface{}{
+				"estimate": 1.5,
+				"billable": true,
+				"client":   `ACME "Inc"`,
+				"a=b":      "key with separator",
+				"nested":   map[string]interface{}{"list": []interface{}{"x", 2.0}},
+			},
+			Annotation: "write docs",
+		},
+		&Interval{ID: "running", Begin: at(14, 11, 30, 0, 0)},
+		&Interval{ID: "duration-only", Begin: cal.DayStart(at(12, 0, 0, 0, 0)), End: cal.DayStart(at(12, 0, 0, 0, 0)), Duration: 90 * time.Minute},
+		&Interval{ID: "duration-at-noon", Begin: at(12, 12, 0, 0, 0), End: at(12, 12, 0, 0, 0), Duration: time.Hour},
+		&Interval{ID: "past-midnight", Begin: at(12, 23, 0, 0, 0), End: at(13, 1, 30, 0, 0)},
+		&Interval{ID: "after-midnight", Begin: at(13, 2, 0, 0, 0), End: at(13, 3, 0, 0, 0)},
+		&Interval{ID: "seconds", Begin: at(13, 9, 0, 1, 0), End: at(13, 9, 30, 59, 123456789)},
+		&Interval{ID: "days", Begin: at(10, 9, 0, 0, 0), End: at(12, 9, 0, 0, 0)},
+	)
+	for _, i := range intervals {
+		i.Status = statusOf(i)
+		i.Raw = formatRaw(i)
+	}
+
+	var buf bytes.Buffer
+	writeEditFile(&buf, intervals, []string{KeyAll}, cal)
+	parsed, err := parseEditFile(&buf, cal, nil)
+	assert.NoError(t, err)
+	if !assert.Len(t, parsed, len(intervals)) {
+		return
+	}
+	for n, i := range intervals {
+		assert.Equal(t, *i, parsed[n], i.ID)
+	}
+}
+
+func TestParseEditLine(t *testing.T) {
+	cal := Calendar{Clock: FixedClock{Time: time.Date(2022, 1, 14, 12, 0, 0, 0, time.UTC)}}
+
+	i, err := parseEditLine(`-  2022-01-14  09:00  10:30  -  fix the README +docs proj:gott.docs "and more"`, cal)
+	assert.NoError(t, err)
+	assert.Equal(t, "", i.ID)
+	assert.Equal(t, time.Date(2022, 1, 14, 9, 0, 0, 0, time.UTC), i.Begin)
+	assert.Equal(t, time.Date(2022, 1, 14, 10, 30, 0, 0, time.UTC), i.End)
+	assert.Equal(t, "fix the README and more", i.Annotation)
+	assert.Equal(t, "gott.docs", i.Project)
+	assert.Equal(t, []string{"docs"}, i.Tags)
+	assert.Equal(t, StatusEnded, i.Status)
+
+	i, err = parseEditLine(`- 2022-01-14 - - 2h`, cal)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, i.Duration)
+	assert.Equal(t, i.Begin, i.End)
+
+	for _, line := range []string{
+		`- 2022-01-14 09:00`,
+		`- 14.01.2022 09:00 10:00 -`,
+		`- 2022-01-14 9am 10:00 -`,
+		`- 2022-01-14 - 10:00 -`,
+		`- 2022-01-14 - - 2 hours`,
+		`- 2022-01-14 09:00 10:00 - "unclosed`,
+		`- 2022-01-14 09:00 10:00 - uda:key`,
+		`- 2022-01-14 09:00 10:00 - uda:key=notjson`,
+	} {
+		_, err := parseEditLine(line, cal)
+		assert.Error(t, err, line)
+	}
+
+	_, err = parseEditFile(strings.NewReader("# comment\n\n- 2022-01-14 09:00 oops -\n"), cal, nil)
+	assert.EqualError(t, err, "line 3: invalid time oops. use HH:MM[:SS] or YYYY-MM-DDTHH:MM[:SS]")
+
+	intervals, err := parseEditFile(strings.NewReader("- 2022-01-14 09:00 - -\n"), cal, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, intervals, 1) {
+		assert.True(t, intervals[0].End.IsZero())
+	}
+	_, err = parseEditFile(strings.NewReader("- 2022-01-14 09:00 - -\n- 2022-01-14 10:00 - -\n"), cal, nil)
+	assert.EqualError(t, err, "line 2: the interval in line 1 is running already. only one can run")
+}
+
+func TestAnnotateEditFile(t *testing.T) {
+	cal := Calendar{Clock: FixedClock{Time: time.Date(2022, 1, 14, 12, 0, 0, 0, time.UTC)}}
+	content := []byte("# header\n\nid-1 2022-01-14 09:00 10:00 -\nid-1 2022-01-14 11:00 12:00 -\n")
+
+	_, err := parseEditFile(bytes.NewReader(content), cal, nil)
+	var lineErr *editLineError
+	if !assert.ErrorAs(t, err, &lineErr) {
+		return
+	}
+	assert.Equal(t, 4, lineErr.Line)
+
+	annotated := annotateEditFile(content, lineErr)
+	assert.Equal(t, "# header\n\nid-1 2022-01-14 09:00 10:00 -\n# ERROR: id id-1 is used in another line\nid-1 2022-01-14 11:00 12:00 -\n", string(annotated))
+
+	// the error is replaced by the next one
+	_, err = parseEditFile(bytes.NewReader(annotated), cal, nil)
+	assert.ErrorAs(t, err, &lineErr)
+	assert.Equal(t, 5, lineErr.Line)
+	fixed := bytes.Replace(annotated, []byte("id-1 2022-01-14 11"), []byte("- 2022-01-14 11"), 1)
+	assert.Equal(t, string(content), string(annotateEditFile(annotated, nil)))
+	_, err = parseEditFile(bytes.NewReader(fixed), cal, nil)
+	assert.NoError(t, err)
+
+	// invalid intervals are errors of their line
+	_, err = parseEditFile(strings.NewReader("- 2022-01-14 10:00 2022-01-14T09:00 -\n"), cal, nil)
+	assert.ErrorAs(t, err, &lineErr)
+	assert.Equal(t, 1, lineErr.Line)
+}
+
+func TestDiffEdit(t *testing.T) {
+	app, _ := newTestApp(t)
+	begin := time.Date(2022, 1, 14, 9, 0, 0, 0, time.UTC)
+	kept := &Interval{ID: "kept", Begin: begin, End: begin.Add(time.Hour), Status: StatusEnded}
+	changed := &Interval{ID: "changed", Begin: begin.Add(time.Hour), End: begin.Add(2 * time.Hour), Status: StatusEnded}
+	deleted := &Interval{ID: "deleted", Begin: begin.Add(2 * time.Hour), End: begin.Add(3 * time.Hour), Status: StatusEnded}
+
+	edited := *changed
+	edited.UDA = map[string]interface{}{"estimate": 2.0}
+	added := Interval{Begin: begin.Add(4 * time.Hour), End: begin.Add(5 * time.Hour), Status: StatusEnded}
+
+	c := app.diffEdit([]*Interval{kept, changed, deleted}, []Interval{*kept, edited, added})
+	assert.Equal(t, 3, c.Count())
+	if assert.Len(t, c.Added, 1) {
+		assert.NotEmpty(t, c.Added[0].ID)
+	}
+	assert.Equal(t, []Interval{edited}, c.Changed)
+	assert.Equal(t, []*Interval{deleted}, c.Deleted)
+	assert.Equal(t, []string{`uda: "" -> "{\"estimate\":2}"`}, app.describeChanges(changed, &edited))
+}
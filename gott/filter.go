@@ -1,19 +1,45 @@
 package gott
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"github.com/satishvis/gott/gott/dateexpr"
+)
 
 const (
-	KeyToday     = ":today"
-	KeyYesterday = ":yesterday"
-	KeyWeek      = ":week"
-	KeyMonth     = ":month"
+	KeyToday     = dateexpr.Today
+	KeyYesterday = dateexpr.Yesterday
+	KeyWeek      = dateexpr.Week
+	KeyMonth     = dateexpr.Month
 	KeyAll       = ":all"
 )
 
-var Keys = []string{KeyToday, KeyYesterday, KeyWeek, KeyMonth, KeyAll}
+// Keys are the keywords of the filter expression.
+var Keys = append(append([]string{}, dateexpr.Keywords...), KeyAll)
 
 type filterFunc = func(i *Interval) bool
 
+// matchWildcard matches s against pattern, where * matches any text.
+func matchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(s, part)
+		if idx < 0 {
+			return false
+		}
+		s = s[idx+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
+
 func containsString(s []string, str string) bool {
 	for _, v := range s {
 		if v == str {
@@ -23,9 +49,27 @@ func containsString(s []string, str string) bool {
 	return false
 }
 
+// createProjectFilter matches the project and its subprojects, e.g. gott
+// matches gott.docs. The project may contain * wildcards.
 func createProjectFilter(project string) filterFunc {
 	return func(i *Interval) bool {
-		return i.Project == project
+		return matchWildcard(project, i.Project) || strings.HasPrefix(i.Project, project+".")
+	}
+}
+
+// createRefFilter matches the ref. It may contain * wildcards.
+func createRefFilter(ref string) filterFunc {
+	return func(i *Interval) bool {
+		return matchWildcard(ref, i.Ref)
+	}
+}
+
+// createTextFilter matches intervals containing text in the annotation,
+// ignoring the case.
+func createTextFilter(text string) filterFunc {
+	text = strings.ToLower(text)
+	return func(i *Interval) bool {
+		return strings.Contains(strings.ToLower(i.Annotation), text)
 	}
 }
 
@@ -35,27 +79,227 @@ func createTagFilter(tag string) filterFunc {
 	}
 }
 
-func createDateFilter(t time.Time) filterFunc {
+// createDateFilter matches intervals beginning on the working day of t in the
+// location of t.
+func createDateFilter(cal Calendar, t time.Time) filterFunc {
+	return createDateRangeFilter(cal, t, t)
+}
+
+// createDateRangeFilter matches intervals beginning between the start of the
+// working day of from and the end of the working day of to.
+func createDateRangeFilter(cal Calendar, from, to time.Time) filterFunc {
+	return createBeginFilter(cal.StartOfDay(from), cal.EndOfDay(to))
+}
+
+// createBeginFilter matches intervals beginning from from until before to.
+// Zero times are open sides.
+func createBeginFilter(from, to time.Time) filterFunc {
+	return func(i *Interval) bool {
+		if !from.IsZero() && i.Begin.Before(from) {
+			return false
+		}
+		if !to.IsZero() && !i.Begin.Before(to) {
+			return false
+		}
+		return true
+	}
+}
+
+// createAndFilter matches intervals matching all filters.
+func createAndFilter(flist ...filterFunc) filterFunc {
 	return func(i *Interval) bool {
-		a := i.Begin.Truncate(24 * time.Hour)
-		b := t.Truncate(24 * time.Hour)
-		return a.Equal(b)
+		for _, ffunc := range flist {
+			if !(ffunc(i)) {
+				return false
+			}
+		}
+		return true
 	}
 }
 
-func createDateRangeFilter(from, to time.Time) filterFunc {
-	from = from.Truncate(24 * time.Hour)
-	to = to.Truncate(24*time.Hour).AddDate(0, 0, 1)
+// createOrFilter matches intervals matching any of the filters.
+func createOrFilter(flist ...filterFunc) filterFunc {
 	return func(i *Interval) bool {
-		return i.Begin.After(from) && i.Begin.Before(to)
+		for _, ffunc := range flist {
+			if ffunc(i) {
+				return true
+			}
+		}
+		return false
 	}
 }
 
-func applyFilter(i *Interval, flist []filterFunc) bool {
-	for _, ffunc := range flist {
-		if !(ffunc(i)) {
+func createNotFilter(ffunc filterFunc) filterFunc {
+	return func(i *Interval) bool {
+		return !ffunc(i)
+	}
+}
+
+// createUDAFilter matches intervals with a value of the uda from from to to,
+// both included. Open sides are nil. Without both it matches intervals
+// without the uda.
+func createUDAFilter(uda UDA, from, to interface{}) filterFunc {
+	return func(i *Interval) bool {
+		value, found := i.UDA[uda.Name]
+		if from == nil && to == nil {
+			return !found
+		}
+		if !found {
 			return false
 		}
+		if from != nil {
+			if c, ok := uda.compare(value, from); !ok || c < 0 {
+				return false
+			}
+		}
+		if to != nil {
+			if c, ok := uda.compare(value, to); !ok || c > 0 {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// filterExpr is a parsed filter expression. cond is a condition on the
+// intervals table of DatabaseSqlite with the parameters args, which every
+// matching interval fulfills, so only candidates are loaded. It is exact if
+// it matches the same intervals as match, only then it can be negated.
+type filterExpr struct {
+	match filterFunc
+	cond  string
+	args  []interface{}
+	exact bool
+}
+
+// sqlTrue is the condition of filters sql can not express.
+const sqlTrue = "1"
+
+// inexactExpr is a filter without sql condition.
+func inexactExpr(f filterFunc) filterExpr {
+	return filterExpr{match: f, cond: sqlTrue}
+}
+
+func andExpr(exprs []filterExpr) filterExpr {
+	result := filterExpr{exact: true}
+	var funcs []filterFunc
+	var conds []string
+	for _, e := range exprs {
+		funcs = append(funcs, e.match)
+		result.exact = result.exact && e.exact
+		if e.cond != sqlTrue {
+			conds = append(conds, "("+e.cond+")")
+			result.args = append(result.args, e.args...)
+		}
+	}
+	result.match = createAndFilter(funcs...)
+	result.cond = sqlTrue
+	if len(conds) > 0 {
+		result.cond = strings.Join(conds, " AND ")
+	}
+	return result
+}
+
+func orExpr(exprs []filterExpr) filterExpr {
+	result := filterExpr{exact: true}
+	var funcs []filterFunc
+	var conds []string
+	all := false
+	for _, e := range exprs {
+		funcs = append(funcs, e.match)
+		result.exact = result.exact && e.exact
+		all = all || e.cond == sqlTrue
+		conds = append(conds, "("+e.cond+")")
+		result.args = append(result.args, e.args...)
+	}
+	result.match = createOrFilter(funcs...)
+	result.cond = strings.Join(conds, " OR ")
+	if all {
+		result.cond, result.args = sqlTrue, nil
+	}
+	return result
+}
+
+// notExpr negates the filter. Without an exact condition all intervals are
+// candidates.
+func notExpr(e filterExpr) filterExpr {
+	if !e.exact {
+		return inexactExpr(createNotFilter(e.match))
+	}
+	return filterExpr{match: createNotFilter(e.match), cond: "NOT (" + e.cond + ")", args: e.args, exact: true}
+}
+
+func tagExpr(tag string) filterExpr {
+	return filterExpr{
+		match: createTagFilter(tag),
+		cond:  "EXISTS (SELECT 1 FROM tags WHERE tags.interval_id = intervals.id AND tags.tag = ?)",
+		args:  []interface{}{tag},
+		exact: true,
+	}
+}
+
+func projectExpr(project string) filterExpr {
+	return filterExpr{
+		match: createProjectFilter(project),
+		cond:  "project GLOB ? OR project GLOB ?",
+		args:  []interface{}{globPattern(project, true), globPattern(project, false) + ".*"},
+		exact: true,
+	}
+}
+
+// beginExpr matches intervals beginning from from until before to. Zero times
+// are open sides.
+func beginExpr(from, to time.Time) filterExpr {
+	result := filterExpr{match: createBeginFilter(from, to), exact: true}
+	var conds []string
+	if !from.IsZero() {
+		conds = append(conds, "begin_at >= ?")
+		result.args = append(result.args, from.UnixNano())
+	}
+	if !to.IsZero() {
+		conds = append(conds, "begin_at < ?")
+		result.args = append(result.args, to.UnixNano())
+	}
+	result.cond = sqlTrue
+	if len(conds) > 0 {
+		result.cond = strings.Join(conds, " AND ")
+	}
+	return result
+}
+
+// overlapExpr matches the intervals which may share time with begin until
+// end: they begin before end and end after begin or are running. Intervals
+// with only a duration are matched if they begin in the time.
+func overlapExpr(begin, end time.Time) filterExpr {
+	return filterExpr{
+		match: func(i *Interval) bool {
+			switch {
+			case !i.Begin.Before(end):
+				return false
+			case i.End.IsZero():
+				return true
+			case i.End.Equal(i.Begin):
+				return !i.Begin.Before(begin)
+			}
+			return i.End.After(begin)
+		},
+		cond:  "begin_at < ? AND (end_at IS NULL OR end_at > ? OR (end_at = begin_at AND begin_at >= ?))",
+		args:  []interface{}{end.UnixNano(), begin.UnixNano(), begin.UnixNano()},
+		exact: true,
+	}
+}
+
+// globPattern escapes s for sql GLOB. With wildcards * matches any text like
+// in matchWildcard.
+func globPattern(s string, wildcards bool) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '[', r == '?', r == '*' && !wildcards:
+			b.WriteString("[" + string(r) + "]")
+		default:
+			b.WriteRune(r)
+		}
 	}
-	return true
+	return b.String()
 }
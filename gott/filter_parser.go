@@ -0,0 +1,286 @@
+package gott
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/satishvis/gott/gott/dateexpr"
+)
+
+const (
+	FilterAnd        = "and"
+	FilterOr         = "or"
+	FilterNot        = "not"
+	FilterRangeSep   = ".."
+	FilterTagExclude = "-"
+)
+
+// parseFilter parses the filter expression in args. It understands the
+// prefixes of the lexer and combines them with and, or, not and parentheses.
+// Terms next to each other are combined with and.
+//
+//	:today :lastweek monday 3d ago ...    date expressions, see dateexpr
+//	:all                                  all intervals
+//	2022-01-01..2022-01-31                a range of date expressions
+//	proj:gott project:gott                project and its subprojects
+//	+tag -tag                             with or without tag
+//	ref:ID-*                              ref, * matches any text
+//	estimate:2h estimate:1h..4h estimate:   declared udas, a range or none
+//	text                                  annotation containing text
+//
+// An empty expression matches every interval.
+func parseFilter(args []string, cal Calendar, udas UDAs) (filterFunc, error) {
+	f, err := parseFilterExpr(args, cal, udas)
+	return f.match, err
+}
+
+// parseFilterExpr parses the filter expression like parseFilter and also
+// returns its sql condition.
+func parseFilterExpr(args []string, cal Calendar, udas UDAs) (filterExpr, error) {
+	p := &filterParser{tokens: tokenizeFilter(args), cal: cal, udas: udas}
+	if len(p.tokens) == 0 {
+		return andExpr(nil), nil
+	}
+	f, err := p.parseOr()
+	if err != nil {
+		return f, err
+	}
+	if p.pos < len(p.tokens) {
+		return f, fmt.Errorf("unexpected %s", p.tokens[p.pos])
+	}
+	return f, nil
+}
+
+// tokenizeFilter splits parentheses from the args, so (proj:a or proj:b)
+// does not need spaces around the parentheses.
+func tokenizeFilter(args []string) []string {
+	var tokens []string
+	for _, arg := range args {
+		for _, field := range strings.Fields(arg) {
+			for strings.HasPrefix(field, "(") {
+				tokens = append(tokens, "(")
+				field = field[1:]
+			}
+			closing := 0
+			for strings.HasSuffix(field, ")") {
+				closing++
+				field = field[:len(field)-1]
+			}
+			if field != "" {
+				tokens = append(tokens, field)
+			}
+			for ; closing > 0; closing-- {
+				tokens = append(tokens, ")")
+			}
+		}
+	}
+	return tokens
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+	cal    Calendar
+	udas   UDAs
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *filterParser) parseOr() (filterExpr, error) {
+	f, err := p.parseAnd()
+	if err != nil {
+		return f, err
+	}
+	filters := []filterExpr{f}
+	for strings.EqualFold(p.peek(), FilterOr) {
+		p.next()
+		f, err := p.parseAnd()
+		if err != nil {
+			return f, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return orExpr(filters), nil
+}
+
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	f, err := p.parseNot()
+	if err != nil {
+		return f, err
+	}
+	filters := []filterExpr{f}
+	for {
+		t := p.peek()
+		if t == "" || t == ")" || strings.EqualFold(t, FilterOr) {
+			break
+		}
+		if strings.EqualFold(t, FilterAnd) {
+			p.next()
+		}
+		f, err := p.parseNot()
+		if err != nil {
+			return f, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return andExpr(filters), nil
+}
+
+func (p *filterParser) parseNot() (filterExpr, error) {
+	if strings.EqualFold(p.peek(), FilterNot) {
+		p.next()
+		f, err := p.parseNot()
+		if err != nil {
+			return f, err
+		}
+		return notExpr(f), nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterExpr, error) {
+	t := p.next()
+	switch {
+	case t == "":
+		return filterExpr{}, fmt.Errorf("unexpected end of filter")
+	case t == "(":
+		f, err := p.parseOr()
+		if err != nil {
+			return f, err
+		}
+		if p.next() != ")" {
+			return f, fmt.Errorf("missing )")
+		}
+		return f, nil
+	case t == ")":
+		return filterExpr{}, fmt.Errorf("unexpected )")
+	case strings.EqualFold(t, FilterAnd), strings.EqualFold(t, FilterOr):
+		return filterExpr{}, fmt.Errorf("unexpected %s", t)
+	}
+	return p.parseTerm(t)
+}
+
+func (p *filterParser) parseTerm(t string) (filterExpr, error) {
+	if tag := strings.TrimPrefix(t, TagPrefix); tag != t && tag != "" {
+		return tagExpr(tag), nil
+	}
+	if proj := strings.TrimPrefix(t, ProjectPrefixShort); proj != t {
+		return projectExpr(proj), nil
+	}
+	if proj := strings.TrimPrefix(t, ProjectPrefix); proj != t {
+		return projectExpr(proj), nil
+	}
+	if ref := strings.TrimPrefix(t, RefPrefix); ref != t {
+		return filterExpr{match: createRefFilter(ref), cond: "ref GLOB ?", args: []interface{}{globPattern(ref, true)}, exact: true}, nil
+	}
+	if uda, text, found := p.udas.lex(t); found {
+		f, err := parseUDAFilter(uda, text)
+		return inexactExpr(f), err
+	}
+	if t == KeyAll {
+		return andExpr(nil), nil
+	}
+	if idx := strings.Index(t, FilterRangeSep); idx >= 0 {
+		return p.parseRange(t[:idx], t[idx+len(FilterRangeSep):])
+	}
+	// dates like 3d ago span several tokens. -2w is a date, not a tag
+	r, n, err := dateexpr.Parse(p.tokens[p.pos-1:], p.today())
+	if err != nil {
+		return filterExpr{}, err
+	}
+	if n > 0 {
+		p.pos += n - 1
+		return beginExpr(p.cal.StartOfDay(p.cal.DayStart(r.From)), p.cal.EndOfDay(p.cal.DayStart(r.To))), nil
+	}
+	if tag := strings.TrimPrefix(t, FilterTagExclude); tag != t && tag != "" {
+		return notExpr(tagExpr(tag)), nil
+	}
+	return inexactExpr(createTextFilter(t)), nil
+}
+
+// parseRange parses the range from..to of date expressions. Either side may
+// be empty for an open range.
+func (p *filterParser) parseRange(from, to string) (filterExpr, error) {
+	var begin, end time.Time
+	if from != "" {
+		r, err := p.parseRangeDate(from)
+		if err != nil {
+			return filterExpr{}, err
+		}
+		begin = p.cal.DayStart(r.From)
+	}
+	if to != "" {
+		r, err := p.parseRangeDate(to)
+		if err != nil {
+			return filterExpr{}, err
+		}
+		end = p.cal.EndOfDay(p.cal.DayStart(r.To))
+	}
+	return beginExpr(begin, end), nil
+}
+
+func (p *filterParser) parseRangeDate(value string) (dateexpr.Range, error) {
+	r, n, err := dateexpr.Parse([]string{value}, p.today())
+	if err != nil {
+		return r, err
+	}
+	if n == 0 {
+		return r, fmt.Errorf("invalid date %s in range", value)
+	}
+	return r, nil
+}
+
+// today returns the date of the current working day.
+func (p *filterParser) today() time.Time {
+	return p.cal.Day(p.cal.Now())
+}
+
+// parseUDAFilter parses the value, the range from..to of values or nothing for
+// intervals without the uda. Either side of a range may be empty.
+func parseUDAFilter(uda UDA, text string) (filterFunc, error) {
+	if text == "" {
+		return createUDAFilter(uda, nil, nil), nil
+	}
+	idx := strings.Index(text, FilterRangeSep)
+	if idx < 0 {
+		value, err := uda.Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		return createUDAFilter(uda, value, value), nil
+	}
+	if uda.Type != UDATypeNumber && uda.Type != UDATypeDuration && uda.Type != UDATypeDate {
+		return nil, fmt.Errorf("%s of type %s has no range", uda.Name, uda.Type)
+	}
+	var from, to interface{}
+	var err error
+	if t := text[:idx]; t != "" {
+		if from, err = uda.Parse(t); err != nil {
+			return nil, err
+		}
+	}
+	if t := text[idx+len(FilterRangeSep):]; t != "" {
+		if to, err = uda.Parse(t); err != nil {
+			return nil, err
+		}
+	}
+	return createUDAFilter(uda, from, to), nil
+}
@@ -0,0 +1,76 @@
+package gott
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	now := time.Date(2022, 1, 19, 12, 0, 0, 0, time.UTC)
+	cal := Calendar{Clock: FixedClock{Time: now}}
+
+	interval := func(day int, project, ref, annotation string, tags ...string) *Interval {
+		begin := time.Date(2022, 1, day, 10, 0, 0, 0, time.UTC)
+		return &Interval{Begin: begin, End: begin.Add(time.Hour), Project: project, Ref: ref, Annotation: annotation, Tags: tags}
+	}
+	intervals := []*Interval{
+		interval(3, "gott", "GOTT-1", "write parser", "dev"),
+		interval(10, "gott.docs", "GOTT-2", "Write README", "docs"),
+		interval(17, "other", "", "meeting", "billable"),
+		interval(19, "gottish", "X-1", "review", "dev", "billable"),
+	}
+
+	matches := func(args ...string) []string {
+		filter, err := parseFilter(args, cal, nil)
+		assert.NoError(t, err, args)
+		var result []string
+		for _, i := range intervals {
+			if filter(i) {
+				result = append(result, i.Annotation)
+			}
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"write parser", "Write README", "meeting", "review"}, matches())
+	assert.Equal(t, []string{"write parser", "Write README"}, matches("proj:gott"))
+	assert.Equal(t, []string{"write parser", "Write README", "review"}, matches("project:gott*"))
+	assert.Equal(t, []string{"write parser", "review"}, matches("+dev"))
+	assert.Equal(t, []string{"Write README", "meeting"}, matches("-dev"))
+	assert.Equal(t, []string{"write parser", "Write README"}, matches("ref:GOTT-*"))
+	assert.Equal(t, []string{"write parser", "Write README"}, matches("write"))
+	assert.Equal(t, []string{"review"}, matches("+dev", "+billable"))
+	assert.Equal(t, []string{"review"}, matches("+dev and +billable"))
+	assert.Equal(t, []string{"Write README", "meeting", "review"}, matches("+docs or +billable"))
+	assert.Equal(t, []string{"Write README", "meeting"}, matches("(+docs or +billable) not +dev"))
+	assert.Equal(t, []string{"write parser", "meeting", "review"}, matches("not", "(proj:gott.docs)"))
+	assert.Equal(t, []string{"Write README", "meeting"}, matches("2022-01-10..2022-01-17"))
+	assert.Equal(t, []string{"meeting", "review"}, matches("2022-01-17.."))
+	assert.Equal(t, []string{"write parser"}, matches("..2022-01-09"))
+	assert.Equal(t, []string{"meeting", "review"}, matches(KeyWeek))
+	assert.Equal(t, []string{"review"}, matches(KeyToday, "or", "2022-01-03", "+billable"))
+	assert.Equal(t, []string{"Write README"}, matches(":lastweek"))
+	assert.Equal(t, []string{"meeting"}, matches("2", "days", "ago"))
+	assert.Equal(t, []string{"meeting"}, matches("monday"))
+	assert.Equal(t, []string{"Write README", "meeting"}, matches("-9d..monday"))
+	assert.Equal(t, []string{"write parser"}, matches("2022-W01", "or", "-2w"))
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	cal := Calendar{Clock: SystemClock{}}
+	for _, args := range [][]string{
+		{"(proj:gott"},
+		{"proj:gott)"},
+		{"+dev", "or"},
+		{"not"},
+		{"and", "+dev"},
+		{":tomorrow"},
+		{"2022-13-01"},
+		{"2022-01-01..soon"},
+	} {
+		_, err := parseFilter(args, cal, nil)
+		assert.Error(t, err, args)
+	}
+}
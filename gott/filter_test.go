@@ -0,0 +1,50 @@
+package gott
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterKeywordsUseClock(t *testing.T) {
+	// wednesday
+	now := time.Date(2022, 1, 19, 12, 0, 0, 0, time.UTC)
+	db := NewDatabaseJson("", Calendar{Clock: FixedClock{Time: now}})
+
+	at := func(day int, annotation string) {
+		i := NewInterval([]string{annotation})
+		i.Begin = time.Date(2022, 1, day, 10, 0, 0, 0, time.UTC)
+		i.End = i.Begin.Add(time.Hour)
+		db.Append(i)
+	}
+	at(1, "first of month")
+	at(16, "last week")
+	at(18, "yesterday")
+	at(19, "today")
+
+	annotations := func(args ...string) []string {
+		intervals, err := db.Filter(args)
+		assert.NoError(t, err)
+		var result []string
+		for _, i := range intervals {
+			result = append(result, i.Annotation)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"today"}, annotations(KeyToday))
+	assert.Equal(t, []string{"yesterday"}, annotations(KeyYesterday))
+	assert.Equal(t, []string{"yesterday", "today"}, annotations(KeyWeek))
+	assert.Equal(t, []string{"first of month", "last week", "yesterday", "today"}, annotations(KeyMonth))
+	assert.Equal(t, []string{"first of month", "last week", "yesterday", "today"}, annotations(KeyAll))
+}
+
+func TestParseNow(t *testing.T) {
+	got, err := parseNow("2022-01-14 09:30", time.Local)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 1, 14, 9, 30, 0, 0, time.Local), got)
+
+	_, err = parseNow("yesterday", time.Local)
+	assert.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package gott
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HandlePrefix starts the short handle of an interval like @1.
+const HandlePrefix = "@"
+
+// isHandle reports whether arg is a short handle like @1.
+func isHandle(arg string) bool {
+	n, err := strconv.Atoi(strings.TrimPrefix(arg, HandlePrefix))
+	return strings.HasPrefix(arg, HandlePrefix) && err == nil && n > 0
+}
+
+// handleIDs returns the ids of all intervals ordered by their handle. @1 is
+// the interval which began last, @2 the one before and so on. Handles stay the
+// same until an interval is added before them. Only the ids are loaded, so
+// numbering does not read the whole history.
+func handleIDs(db Database) ([]string, error) {
+	ids, err := db.IDs()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(ids))
+	for n, id := range ids {
+		result[len(ids)-1-n] = id
+	}
+	return result, nil
+}
+
+// handles returns the handles of all intervals by id.
+func handles(db Database) (map[string]string, error) {
+	ids, err := handleIDs(db)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, len(ids))
+	for n, id := range ids {
+		result[id] = HandlePrefix + strconv.Itoa(n+1)
+	}
+	return result, nil
+}
+
+// resolveInterval returns the interval addressed by a handle like @1 or by its
+// id.
+func resolveInterval(db Database, arg string) (*Interval, error) {
+	if !isHandle(arg) {
+		if i, found := db.Get(arg); found {
+			return i, nil
+		}
+		return nil, fmt.Errorf("%s is neither a handle like %s1 nor an interval id", arg, HandlePrefix)
+	}
+	n, _ := strconv.Atoi(strings.TrimPrefix(arg, HandlePrefix))
+	ids, err := handleIDs(db)
+	if err != nil {
+		return nil, err
+	}
+	if n > len(ids) {
+		return nil, fmt.Errorf("there is no interval %s. there are only %d intervals", arg, len(ids))
+	}
+	i, found := db.Get(ids[n-1])
+	if !found {
+		return nil, fmt.Errorf("there is no interval %s", arg)
+	}
+	return i, nil
+}
@@ -0,0 +1,60 @@
+package gott
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveInterval(t *testing.T) {
+	sqliteDB := NewDatabaseSqlite(filepath.Join(t.TempDir(), "db.sqlite"), Calendar{Clock: SystemClock{}})
+	assert.NoError(t, sqliteDB.Load())
+	defer sqliteDB.Close()
+	for name, db := range map[string]Database{
+		"json":   NewDatabaseJson("", Calendar{Clock: SystemClock{}}),
+		"sqlite": sqliteDB,
+	} {
+		t.Run(name, func(t *testing.T) { testResolveInterval(t, db) })
+	}
+}
+
+func testResolveInterval(t *testing.T, db Database) {
+	for day, annotation := range []string{"first", "second", "third"} {
+		i := NewInterval([]string{annotation})
+		i.Begin = time.Date(2022, 1, 10+day, 9, 0, 0, 0, time.UTC)
+		i.End = i.Begin.Add(time.Hour)
+		assert.NoError(t, db.Append(i))
+	}
+
+	latest, err := resolveInterval(db, "@1")
+	assert.NoError(t, err)
+	assert.Equal(t, "third", latest.Annotation)
+
+	earliest, err := resolveInterval(db, "@3")
+	assert.NoError(t, err)
+	assert.Equal(t, "first", earliest.Annotation)
+
+	byID, err := resolveInterval(db, earliest.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, earliest, byID)
+
+	ids, err := handles(db)
+	assert.NoError(t, err)
+	assert.Equal(t, "@3", ids[earliest.ID])
+
+	for _, arg := range []string{"@4", "@0", "@x", "first"} {
+		_, err := resolveInterval(db, arg)
+		assert.Error(t, err, arg)
+	}
+
+	// handles follow changed begins
+	moved := *earliest
+	moved.Begin = time.Date(2022, 1, 20, 9, 0, 0, 0, time.UTC)
+	moved.End = moved.Begin.Add(time.Hour)
+	assert.NoError(t, db.Apply(moved))
+	latest, err = resolveInterval(db, "@1")
+	assert.NoError(t, err)
+	assert.Equal(t, "first", latest.Annotation)
+}
@@ -0,0 +1,400 @@
+package gott
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const journalSuffix = ".journal"
+
+// JournalChange is the state of one interval before and after a command.
+// Before is nil for created intervals, After is nil for removed ones.
+type JournalChange struct {
+	Before *Interval `json:",omitempty"`
+	After  *Interval `json:",omitempty"`
+}
+
+// JournalEntry holds all changes of one gott invocation.
+type JournalEntry struct {
+	Seq           int
+	Time          time.Time
+	Command       string
+	CurrentBefore string
+	CurrentAfter  string
+	Changes       []JournalChange
+	// Undoes lists the entries reverted by this entry
+	Undoes []int `json:",omitempty"`
+}
+
+// JournalError is returned by App.Close when the database was saved, but its
+// changes could not be recorded in the journal.
+type JournalError struct {
+	Err error
+}
+
+func (e *JournalError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *JournalError) Unwrap() error {
+	return e.Err
+}
+
+// Journal wraps a Database and records the changes of every mutating call.
+// The recorded changes are appended to the journal file as one entry when
+// they are committed after the database has been saved.
+type Journal struct {
+	Database
+	filename string
+	clock    Clock
+	// Command is the command line recorded with the changes
+	Command string
+
+	// pending changes of this invocation
+	tracked       bool
+	currentBefore string
+	before        map[string]*Interval
+	order         []string
+	undoes        []int
+}
+
+func (j *Journal) track(ids ...string) {
+	if !j.tracked {
+		j.tracked = true
+		if cur, found := j.Database.GetCurrent(); found {
+			j.currentBefore = cur.ID
+		}
+	}
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		if _, found := j.before[id]; found {
+			continue
+		}
+		if i, found := j.Database.Get(id); found {
+			j.before[id] = copyInterval(i)
+		} else {
+			j.before[id] = nil
+		}
+		j.order = append(j.order, id)
+	}
+}
+
+func (j *Journal) currentID() string {
+	if cur, found := j.Database.GetCurrent(); found {
+		return cur.ID
+	}
+	return ""
+}
+
+func (j *Journal) SetCurrent(id string) error {
+	j.track()
+	return j.Database.SetCurrent(id)
+}
+
+func (j *Journal) Start(interval Interval, at time.Time) {
+	j.track(j.currentID(), interval.ID)
+	j.Database.Start(interval, at)
+}
+
+func (j *Journal) Cancel() {
+	j.track(j.currentID())
+	j.Database.Cancel()
+}
+
+func (j *Journal) Stop(at time.Time) {
+	j.track(j.currentID())
+	j.Database.Stop(at)
+}
+
+func (j *Journal) Append(interval Interval) error {
+	j.track(interval.ID)
+	return j.Database.Append(interval)
+}
+
+func (j *Journal) AppendPtr(interval *Interval) error {
+	j.track(interval.ID)
+	return j.Database.AppendPtr(interval)
+}
+
+func (j *Journal) RemoveById(id string) {
+	j.track(id)
+	j.Database.RemoveById(id)
+}
+
+func (j *Journal) Apply(interval Interval) error {
+	j.track(interval.ID)
+	return j.Database.Apply(interval)
+}
+
+// Pending returns the changes recorded since the last commit.
+func (j *Journal) Pending() (JournalEntry, bool) {
+	entry := JournalEntry{
+		Time:          j.clock.Now(),
+		Command:       j.Command,
+		CurrentBefore: j.currentBefore,
+		CurrentAfter:  j.currentID(),
+		Undoes:        j.undoes,
+	}
+	for _, id := range j.order {
+		change := JournalChange{Before: j.before[id]}
+		if i, found := j.Database.Get(id); found {
+			change.After = copyInterval(i)
+		}
+		if change.Before == nil && change.After == nil {
+			continue
+		}
+		if sameInterval(change.Before, change.After) {
+			continue
+		}
+		entry.Changes = append(entry.Changes, change)
+	}
+	changed := len(entry.Changes) > 0 || entry.CurrentBefore != entry.CurrentAfter
+	return entry, j.tracked && changed
+}
+
+// Commit appends the pending changes to the journal file.
+func (j *Journal) Commit() error {
+	entry, changed := j.Pending()
+	if !changed {
+		return nil
+	}
+	return j.CommitEntry(entry)
+}
+
+// CommitEntry appends the entry taken from Pending to the journal file. It
+// is used when the database can't be read anymore after saving.
+func (j *Journal) CommitEntry(entry JournalEntry) error {
+	seq, err := j.lastSeq()
+	if err != nil {
+		return err
+	}
+	entry.Seq = seq + 1
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error marshaling journal entry: %s", err.Error())
+	}
+	f, err := os.OpenFile(j.filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening journal: %s", err.Error())
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing journal: %s", err.Error())
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("error syncing journal: %s", err.Error())
+	}
+
+	j.tracked = false
+	j.currentBefore = ""
+	j.before = make(map[string]*Interval)
+	j.order = nil
+	j.undoes = nil
+	return nil
+}
+
+// journalChunk is the size of the blocks lastSeq reads from the end.
+const journalChunk = 4096
+
+// lastSeq returns the Seq of the last entry of the journal file, 0 without
+// entries. Only the last line is read, so committing does not get slower or
+// fail on lines the scanner of Entries could not read.
+func (j *Journal) lastSeq() (int, error) {
+	f, err := os.Open(j.filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("error opening journal: %s", err.Error())
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("error reading journal: %s", err.Error())
+	}
+
+	// read blocks backwards until the newline before the last line
+	end := info.Size()
+	var line []byte
+	for end > 0 {
+		start := end - journalChunk
+		if start < 0 {
+			start = 0
+		}
+		chunk := make([]byte, end-start)
+		if _, err := f.ReadAt(chunk, start); err != nil {
+			return 0, fmt.Errorf("error reading journal: %s", err.Error())
+		}
+		line = append(chunk, line...)
+		end = start
+		if idx := bytes.LastIndexByte(bytes.TrimRight(line, "\n"), '\n'); idx >= 0 {
+			line = line[idx+1:]
+			break
+		}
+	}
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return 0, nil
+	}
+	var last struct{ Seq int }
+	if err := json.Unmarshal(line, &last); err != nil {
+		return 0, fmt.Errorf("error in the last journal line: %s", err.Error())
+	}
+	return last.Seq, nil
+}
+
+// Entries reads all entries of the journal file, oldest first.
+func (j *Journal) Entries() ([]JournalEntry, error) {
+	f, err := os.Open(j.filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error opening journal: %s", err.Error())
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("error in journal line %d: %s", line, err.Error())
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Undoable returns the entries which can be undone, latest first. Undo
+// entries themselves and already undone entries are skipped.
+func (j *Journal) Undoable() ([]JournalEntry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+	undone := map[int]bool{}
+	for _, e := range entries {
+		for _, seq := range e.Undoes {
+			undone[seq] = true
+		}
+	}
+	var result []JournalEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if len(e.Undoes) > 0 || undone[e.Seq] {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// Undo reverts the latest n undoable entries. Unless force is set it refuses
+// to revert intervals which were changed afterwards by the journaled
+// commands.
+func (j *Journal) Undo(n int, force bool) ([]JournalEntry, error) {
+	undoable, err := j.Undoable()
+	if err != nil {
+		return nil, err
+	}
+	if len(undoable) == 0 {
+		return nil, errors.New("nothing to undo")
+	}
+	if n > len(undoable) {
+		n = len(undoable)
+	}
+	undoable = undoable[:n]
+
+	for _, entry := range undoable {
+		if !force {
+			if err := j.checkUndo(entry); err != nil {
+				return nil, err
+			}
+		}
+		for c := len(entry.Changes) - 1; c >= 0; c-- {
+			change := entry.Changes[c]
+			var err error
+			switch {
+			case change.After == nil:
+				err = j.AppendPtr(copyInterval(change.Before))
+			case change.Before == nil:
+				j.RemoveById(change.After.ID)
+			default:
+				if _, found := j.Database.Get(change.Before.ID); found {
+					err = j.Apply(*copyInterval(change.Before))
+				} else {
+					err = j.AppendPtr(copyInterval(change.Before))
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := j.SetCurrent(entry.CurrentBefore); err != nil {
+			return nil, err
+		}
+		j.undoes = append(j.undoes, entry.Seq)
+	}
+	return undoable, nil
+}
+
+func (j *Journal) checkUndo(entry JournalEntry) error {
+	for _, change := range entry.Changes {
+		var id string
+		if change.After != nil {
+			id = change.After.ID
+		} else {
+			id = change.Before.ID
+		}
+		var now *Interval
+		if i, found := j.Database.Get(id); found {
+			now = copyInterval(i)
+		}
+		if !sameInterval(now, change.After) {
+			return fmt.Errorf("interval %s was changed after #%d (%s). use --force to undo anyway", id, entry.Seq, entry.Command)
+		}
+	}
+	return nil
+}
+
+// sameInterval compares intervals like they are stored.
+func sameInterval(a, b *Interval) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
+
+func copyInterval(i *Interval) *Interval {
+	if i == nil {
+		return nil
+	}
+	c := *i
+	if i.Tags != nil {
+		c.Tags = append([]string{}, i.Tags...)
+	}
+	if i.UDA != nil {
+		c.UDA = make(map[string]interface{}, len(i.UDA))
+		for k, v := range i.UDA {
+			c.UDA[k] = v
+		}
+	}
+	return &c
+}
+
+func NewJournal(db Database, filename, command string, clock Clock) *Journal {
+	return &Journal{
+		Database: db,
+		filename: filename,
+		clock:    clock,
+		Command:  command,
+		before:   make(map[string]*Interval),
+	}
+}
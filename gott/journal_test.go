@@ -0,0 +1,101 @@
+package gott
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournalUndo(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabaseJson(filepath.Join(dir, "db.json"), Calendar{Clock: SystemClock{}})
+	j := NewJournal(db, filepath.Join(dir, "db.json.journal"), "gott test", SystemClock{})
+
+	j.Start(NewInterval([]string{"writing", "+docs"}), time.Now())
+	assert.NoError(t, j.Commit())
+	j.Stop(time.Now())
+	assert.NoError(t, j.Commit())
+	j.Cancel()
+	// nothing running, nothing to record
+	assert.NoError(t, j.Commit())
+
+	entries, err := j.Entries()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	undone, err := j.Undo(1, false)
+	assert.NoError(t, err)
+	assert.NoError(t, j.Commit())
+	if assert.Len(t, undone, 1) {
+		assert.Equal(t, 2, undone[0].Seq)
+	}
+	current, found := j.GetCurrent()
+	assert.True(t, found)
+	assert.True(t, current.End.IsZero())
+	assert.Equal(t, StatusStarted, current.Status)
+
+	// the undo itself is skipped
+	undone, err = j.Undo(5, false)
+	assert.NoError(t, err)
+	assert.NoError(t, j.Commit())
+	assert.Len(t, undone, 1)
+	assert.Equal(t, 0, j.Count())
+
+	_, err = j.Undo(1, false)
+	assert.Error(t, err)
+}
+
+func TestJournalUndoRefusesChanged(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabaseJson(filepath.Join(dir, "db.json"), Calendar{Clock: SystemClock{}})
+	j := NewJournal(db, filepath.Join(dir, "db.json.journal"), "gott test", SystemClock{})
+
+	interval := NewInterval([]string{"cake"})
+	j.Append(interval)
+	assert.NoError(t, j.Commit())
+
+	// changed without the journal
+	i, _ := db.Get(interval.ID)
+	i.Annotation = "bread"
+
+	_, err := j.Undo(1, false)
+	assert.Error(t, err)
+	_, err = j.Undo(1, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, j.Count())
+}
+
+func TestJournalSeqFromLastLine(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "db.json.journal")
+	db := NewDatabaseJson(filepath.Join(dir, "db.json"), Calendar{Clock: SystemClock{}})
+	j := NewJournal(db, filename, "gott test", SystemClock{})
+
+	seq, err := j.lastSeq()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, seq)
+
+	// a line longer than the blocks read from the end
+	j.Start(NewInterval([]string{strings.Repeat("a", 3*journalChunk)}), time.Now())
+	assert.NoError(t, j.Commit())
+	j.Stop(time.Now())
+	assert.NoError(t, j.Commit())
+	seq, err = j.lastSeq()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, seq)
+
+	// earlier lines are not read when committing
+	data, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filename, append([]byte("not json\n"), data...), 0644))
+	now := time.Now()
+	assert.NoError(t, j.AppendPtr(&Interval{ID: "x", Begin: now, End: now, Duration: time.Hour, Status: StatusEnded}))
+	assert.NoError(t, j.Commit())
+	seq, err = j.lastSeq()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, seq)
+}
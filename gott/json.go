@@ -0,0 +1,339 @@
+package gott
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BillableTag is the tag of billable intervals.
+const BillableTag = "billable"
+
+// jsonInterval is an interval of the json export. The schema is stable,
+// fields are only ever added:
+//
+//	id          the id of the interval
+//	start       the begin in RFC 3339
+//	end         the end in RFC 3339, null while running and for intervals
+//	            with only a duration
+//	duration    the duration in ISO 8601 like PT1H30M, null while running
+//	project     the project, "" for none
+//	tags        the tags, [] for none
+//	ref         the ref, "" for none
+//	annotation  the annotation, "" for none
+//	billable    whether the interval has the tag billable
+//	uda         the user defined attributes by name, {} for none
+type jsonInterval struct {
+	ID         string                 `json:"id"`
+	Start      time.Time              `json:"start"`
+	End        *time.Time             `json:"end"`
+	Duration   *isoDuration           `json:"duration"`
+	Project    string                 `json:"project"`
+	Tags       []string               `json:"tags"`
+	Ref        string                 `json:"ref"`
+	Annotation string                 `json:"annotation"`
+	Billable   bool                   `json:"billable"`
+	UDA        map[string]interface{} `json:"uda"`
+}
+
+// newJSONInterval converts the interval with its times in loc.
+func newJSONInterval(i *Interval, loc *time.Location) jsonInterval {
+	j := jsonInterval{
+		ID:         i.ID,
+		Start:      i.Begin.In(loc),
+		Project:    i.Project,
+		Tags:       append([]string{}, i.Tags...),
+		Ref:        i.Ref,
+		Annotation: i.Annotation,
+		Billable:   containsString(i.Tags, BillableTag),
+		UDA:        map[string]interface{}{},
+	}
+	switch {
+	case i.End.Equal(i.Begin):
+		d := isoDuration(i.Duration)
+		j.Duration = &d
+	case !i.End.IsZero():
+		end := i.End.In(loc)
+		d := isoDuration(i.End.Sub(i.Begin))
+		j.End, j.Duration = &end, &d
+	}
+	for name, value := range i.UDA {
+		j.UDA[name] = value
+	}
+	return j
+}
+
+// writeJSON writes the intervals as indented json array or, with lines, as
+// one json object per line.
+func writeJSON(w io.Writer, intervals []*Interval, loc *time.Location, lines bool) error {
+	exported := []jsonInterval{}
+	for _, i := range intervals {
+		exported = append(exported, newJSONInterval(i, loc))
+	}
+	if lines {
+		for _, j := range exported {
+			data, err := json.Marshal(j)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s\n", data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// jsonRecord is an interval of a json import with the fields it has.
+type jsonRecord struct {
+	// N counts the intervals from 1
+	N        int
+	Interval jsonInterval
+	Fields   map[string]bool
+}
+
+func (r jsonRecord) has(field string) bool {
+	return r.Fields[field]
+}
+
+// readJSON reads a json array of intervals or one interval per line.
+func readJSON(r io.Reader) ([]jsonRecord, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var objects []json.RawMessage
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(trimmed, &objects); err != nil {
+			return nil, err
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var object json.RawMessage
+			if err := decoder.Decode(&object); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("interval %d: %s", len(objects)+1, err.Error())
+			}
+			objects = append(objects, object)
+		}
+	}
+
+	var records []jsonRecord
+	for n, object := range objects {
+		record := jsonRecord{N: n + 1, Fields: map[string]bool{}}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(object, &fields); err != nil {
+			return nil, fmt.Errorf("interval %d: %s", record.N, err.Error())
+		}
+		if err := json.Unmarshal(object, &record.Interval); err != nil {
+			return nil, fmt.Errorf("interval %d: %s", record.N, err.Error())
+		}
+		for field := range fields {
+			record.Fields[field] = true
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// applyTo merges the fields of the record into the interval, missing fields
+// keep their value. New intervals need a start. A duration without end
+// makes an interval with only a duration, or moves the end of ended ones. A
+// given billable adds or removes the tag billable.
+func (r jsonRecord) applyTo(i *Interval, isNew bool, udas UDAs) error {
+	j := r.Interval
+	if isNew && !r.has("start") {
+		return errors.New("a new interval needs a start")
+	}
+	raw := formatRaw(i)
+
+	// the end and duration like they are exported
+	var end *time.Time
+	var duration *time.Duration
+	switch {
+	case isNew || i.End.IsZero():
+	case i.End.Equal(i.Begin):
+		d := i.Duration
+		duration = &d
+	default:
+		e := i.End
+		end = &e
+	}
+	if r.has("start") {
+		i.Begin = j.Start
+	}
+	if r.has("end") {
+		end = j.End
+		if !r.has("duration") {
+			duration = nil
+		}
+	}
+	if r.has("duration") {
+		switch {
+		case j.Duration == nil:
+			duration = nil
+		case end != nil && r.has("end"):
+			if end.Sub(i.Begin) != time.Duration(*j.Duration) {
+				return fmt.Errorf("the duration %s does not match the start and end", j.Duration)
+			}
+		case end != nil:
+			e := i.Begin.Add(time.Duration(*j.Duration))
+			end = &e
+		default:
+			d := time.Duration(*j.Duration)
+			duration = &d
+		}
+	}
+	switch {
+	case end != nil:
+		i.End, i.Duration = *end, 0
+	case duration != nil:
+		i.End, i.Duration = i.Begin, *duration
+	default:
+		i.End, i.Duration = time.Time{}, 0
+	}
+	i.Status = statusOf(i)
+
+	if r.has("project") {
+		i.Project = j.Project
+	}
+	if r.has("ref") {
+		i.Ref = j.Ref
+	}
+	if r.has("annotation") {
+		i.Annotation = j.Annotation
+	}
+	if r.has("tags") {
+		i.Tags = nil
+		for _, tag := range j.Tags {
+			if tag != "" && !containsString(i.Tags, tag) {
+				i.Tags = append(i.Tags, tag)
+			}
+		}
+	}
+	if r.has("billable") {
+		billable := containsString(i.Tags, BillableTag)
+		switch {
+		case j.Billable && !billable:
+			i.Tags = append(i.Tags, BillableTag)
+		case !j.Billable && billable:
+			var tags []string
+			for _, tag := range i.Tags {
+				if tag != BillableTag {
+					tags = append(tags, tag)
+				}
+			}
+			i.Tags = tags
+		}
+	}
+	if r.has("uda") {
+		i.UDA = nil
+		for name, value := range j.UDA {
+			if value == nil {
+				continue
+			}
+			if uda, found := udas.Get(name); found {
+				parsed, err := uda.Parse(fmtUDAValue(value))
+				if err != nil {
+					return err
+				}
+				value = parsed
+			}
+			setUDA(i, name, value)
+		}
+	}
+	if isNew {
+		udas.setDefaults(i)
+	}
+
+	// keep the raw text of unchanged intervals
+	if isNew || formatRaw(i) != raw {
+		i.Raw = formatRaw(i)
+	}
+	if err := i.Validate(); err != nil {
+		return err
+	}
+	return udas.Validate(i)
+}
+
+// isoDuration is a duration in ISO 8601 like PT1H30M in json.
+type isoDuration time.Duration
+
+var isoDurationRegexp = regexp.MustCompile(`^P(?:(\d+(?:[.,]\d+)?)W)?(?:(\d+(?:[.,]\d+)?)D)?(?:T(?:(\d+(?:[.,]\d+)?)H)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`)
+
+// String formats the duration in hours, minutes and seconds like PT25H30M.
+// Days are not used, as they may have 23 or 25 hours.
+func (d isoDuration) String() string {
+	duration := time.Duration(d)
+	if duration == 0 {
+		return "PT0S"
+	}
+	var b strings.Builder
+	b.WriteString("PT")
+	if h := duration / time.Hour; h > 0 {
+		fmt.Fprintf(&b, "%dH", h)
+		duration -= h * time.Hour
+	}
+	if m := duration / time.Minute; m > 0 {
+		fmt.Fprintf(&b, "%dM", m)
+		duration -= m * time.Minute
+	}
+	if duration > 0 {
+		b.WriteString(strconv.FormatFloat(duration.Seconds(), 'f', -1, 64) + "S")
+	}
+	return b.String()
+}
+
+// parseISODuration parses ISO 8601 durations with weeks, days, hours,
+// minutes and seconds. Days have 24 hours, years and months are not
+// supported.
+func parseISODuration(text string) (time.Duration, error) {
+	m := isoDurationRegexp.FindStringSubmatch(text)
+	if m == nil || text == "P" || strings.HasSuffix(text, "T") {
+		return 0, fmt.Errorf("invalid duration %s. use ISO 8601 like PT1H30M", text)
+	}
+	var result time.Duration
+	for n, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[n+1] == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.Replace(m[n+1], ",", ".", 1), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %s. use ISO 8601 like PT1H30M", text)
+		}
+		result += time.Duration(f * float64(unit))
+	}
+	return result, nil
+}
+
+func (d isoDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *isoDuration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return errors.New("the duration must be a string like PT1H30M")
+	}
+	parsed, err := parseISODuration(text)
+	if err != nil {
+		return err
+	}
+	*d = isoDuration(parsed)
+	return nil
+}
@@ -0,0 +1,142 @@
+package gott
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestISODuration(t *testing.T) {
+	for d, text := range map[time.Duration]string{
+		0:                                    "PT0S",
+		90 * time.Minute:                     "PT1H30M",
+		25*time.Hour + 1500*time.Millisecond: "PT25H1.5S",
+		45 * time.Second:                     "PT45S",
+	} {
+		assert.Equal(t, text, isoDuration(d).String())
+		parsed, err := parseISODuration(text)
+		assert.NoError(t, err, text)
+		assert.Equal(t, d, parsed, text)
+	}
+
+	for text, d := range map[string]time.Duration{"P1D": 24 * time.Hour, "P1W": 7 * 24 * time.Hour, "PT1,5H": 90 * time.Minute, "P1DT2H": 26 * time.Hour} {
+		parsed, err := parseISODuration(text)
+		assert.NoError(t, err, text)
+		assert.Equal(t, d, parsed, text)
+	}
+	for _, text := range []string{"", "P", "PT", "1h", "P1M", "P1Y", "PT-1H", "PT1H30"} {
+		_, err := parseISODuration(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestJSONApply(t *testing.T) {
+	begin := time.Date(2022, 1, 14, 9, 0, 0, 0, time.UTC)
+	read := func(text string) jsonRecord {
+		records, err := readJSON(strings.NewReader(text))
+		if assert.NoError(t, err, text) && assert.Len(t, records, 1, text) {
+			return records[0]
+		}
+		return jsonRecord{}
+	}
+	ended := func() Interval {
+		return Interval{ID: "a", Begin: begin, End: begin.Add(time.Hour), Status: StatusEnded,
+			Tags: []string{"docs", "billable"}, Project: "gott", Annotation: "writing", Raw: "writing docs proj:gott"}
+	}
+
+	// missing fields are kept and the raw text stays
+	i := ended()
+	assert.NoError(t, read(`{"id":"a","annotation":"writing"}`).applyTo(&i, false, testUDAs))
+	assert.Equal(t, ended(), i)
+
+	i = ended()
+	assert.NoError(t, read(`{"id":"a","billable":false,"uda":{"estimate":"90m","points":2}}`).applyTo(&i, false, testUDAs))
+	assert.Equal(t, []string{"docs"}, i.Tags)
+	assert.Equal(t, map[string]interface{}{"estimate": "1h30m0s", "points": 2.0}, i.UDA)
+	assert.Equal(t, "writing proj:gott +docs estimate:1h30m0s points:2", i.Raw)
+
+	// a duration moves the end, without end it is an interval with only a duration
+	i = ended()
+	assert.NoError(t, read(`{"id":"a","duration":"PT2H"}`).applyTo(&i, false, nil))
+	assert.Equal(t, begin.Add(2*time.Hour), i.End)
+	i = ended()
+	assert.NoError(t, read(`{"id":"a","end":null,"duration":"PT2H"}`).applyTo(&i, false, nil))
+	assert.Equal(t, begin, i.End)
+	assert.Equal(t, 2*time.Hour, i.Duration)
+	i = ended()
+	assert.NoError(t, read(`{"id":"a","end":null}`).applyTo(&i, false, nil))
+	assert.Equal(t, StatusStarted, i.Status)
+
+	i = NewInterval(nil)
+	assert.NoError(t, read(`{"start":"2022-01-14T10:00:00+01:00","end":"2022-01-14T11:00:00+01:00","tags":["docs"],"billable":true}`).applyTo(&i, true, testUDAs))
+	assert.Equal(t, begin, i.Begin.UTC())
+	assert.Equal(t, []string{"docs", "billable"}, i.Tags)
+	assert.Equal(t, "acme", i.UDA["client"])
+
+	for _, text := range []string{
+		`{"annotation":"no start"}`,
+		`{"start":"2022-01-14T10:00:00Z","end":"2022-01-14T09:00:00Z"}`,
+		`{"start":"2022-01-14T10:00:00Z","end":"2022-01-14T11:00:00Z","duration":"PT2H"}`,
+		`{"start":"2022-01-14T10:00:00Z","uda":{"points":"many"}}`,
+	} {
+		i = NewInterval(nil)
+		assert.Error(t, read(text).applyTo(&i, true, testUDAs), text)
+	}
+	for _, text := range []string{`{"start":"today"}`, `{"duration":"2h"}`, `[{"id":1}]`, `{"id":"a"} x`} {
+		_, err := readJSON(strings.NewReader(text))
+		assert.Error(t, err, text)
+	}
+}
+
+func TestAppJSON(t *testing.T) {
+	app, out := newTestApp(t)
+	app.Config.Location = time.UTC
+	app.Config.UDAs = testUDAs
+	runApp(t, app, "track", "2022-01-14", "09:00-10:30", "--", "proj:gott", "+billable", "writing", "estimate:2h")
+	runApp(t, app, "track", "2022-01-14", "2h", "--", "review")
+	runApp(t, app, "start", "--now=2022-01-14 12:00", "--", "call")
+
+	out.Reset()
+	runApp(t, app, "export", "--format", "jsonl", "proj:gott")
+	assert.Regexp(t, `^\{"id":"[0-9a-f-]+","start":"2022-01-14T09:00:00Z","end":"2022-01-14T10:30:00Z","duration":"PT1H30M",`+
+		`"project":"gott","tags":\["billable"\],"ref":"","annotation":"writing","billable":true,"uda":\{"client":"acme","estimate":"2h0m0s"\}\}\n$`, out.String())
+
+	out.Reset()
+	runApp(t, app, "export")
+	exported := out.String()
+	assert.Contains(t, exported, `"duration": "PT2H"`)
+	assert.Contains(t, exported, `"end": null`)
+
+	// importing the unchanged export changes nothing
+	file := filepath.Join(t.TempDir(), "export.json")
+	assert.NoError(t, ioutil.WriteFile(file, []byte(exported), 0600))
+	out.Reset()
+	runApp(t, app, "import", "json", file)
+	assert.Contains(t, out.String(), "added 0 intervals, changed 0, left 3 unchanged")
+
+	// a script changes an interval and adds one
+	records, err := readJSON(strings.NewReader(exported))
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`{"id":"`+records[0].Interval.ID+`","billable":true}
+{"start":"2022-01-13T09:00:00Z","duration":"PT1H","annotation":"planning"}
+`), 0600))
+	out.Reset()
+	runApp(t, app, "import", "json", "--dry-run", file)
+	assert.Contains(t, out.String(), "add 2022-01-13 01:00 planning")
+	assert.Contains(t, out.String(), `tags: "" -> "billable"`)
+	assert.Contains(t, out.String(), "would add 1 intervals, change 1, leave 0 unchanged")
+
+	out.Reset()
+	runApp(t, app, "import", "json", file)
+	assert.Contains(t, out.String(), "added 1 intervals, changed 1, left 0 unchanged")
+	intervals, _ := app.Database.Filter([]string{KeyAll, "+billable"})
+	assert.Len(t, intervals, 2)
+	current, found := app.Database.GetCurrent()
+	if assert.True(t, found) {
+		assert.Equal(t, "call", current.Annotation)
+	}
+}
@@ -0,0 +1,62 @@
+package gott
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	ConfLockTimeout = "locktimeout"
+	lockSuffix      = ".lock"
+	lockRetryDelay  = 50 * time.Millisecond
+)
+
+var errLocked = errors.New("locked")
+
+// fileLock is an advisory lock on a sidecar file of the database. It is held
+// from loading the database until it is saved, so concurrent gott processes
+// (e.g. started by the taskwarrior hook) cannot overwrite each other.
+type fileLock struct {
+	file *os.File
+}
+
+// lockDatabase locks the database file filename. Exclusive locks are needed
+// to modify the database, read-only access only needs a shared lock.
+func lockDatabase(filename string, exclusive bool, timeout time.Duration) (*fileLock, error) {
+	name := filename + lockSuffix
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lock file %s: %s", name, err.Error())
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := lockFile(f, exclusive)
+		if err == nil {
+			return &fileLock{file: f}, nil
+		}
+		if !errors.Is(err, errLocked) {
+			f.Close()
+			return nil, fmt.Errorf("error locking %s: %s", name, err.Error())
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("database %s is locked by another gott process. gave up after %s", filename, timeout)
+		}
+		time.Sleep(lockRetryDelay)
+	}
+}
+
+func (l *fileLock) Unlock() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := unlockFile(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
@@ -0,0 +1,53 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package gott
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockDatabase(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "db.json")
+
+	shared1, err := lockDatabase(filename, false, 0)
+	assert.NoError(t, err)
+	shared2, err := lockDatabase(filename, false, 0)
+	assert.NoError(t, err)
+
+	_, err = lockDatabase(filename, true, 100*time.Millisecond)
+	assert.Error(t, err)
+
+	assert.NoError(t, shared1.Unlock())
+	assert.NoError(t, shared2.Unlock())
+
+	exclusive, err := lockDatabase(filename, true, 0)
+	assert.NoError(t, err)
+	_, err = lockDatabase(filename, false, 0)
+	assert.Error(t, err)
+	assert.NoError(t, exclusive.Unlock())
+}
+
+func TestDoctorLock(t *testing.T) {
+	app, out := newTestApp(t)
+	app.Config.LockTimeout = 100 * time.Millisecond
+	runApp(t, app, "track", "2022-01-14", "1h", "--", "review")
+	shared, err := lockDatabase(app.Config.DatabaseName, false, 0)
+	assert.NoError(t, err)
+	defer shared.Unlock()
+
+	// doctor only reads without --fix
+	out.Reset()
+	runApp(t, app, "doctor")
+	assert.Contains(t, out.String(), "no problems found")
+
+	cmd := NewRootCmd(app)
+	cmd.SetArgs([]string{"doctor", "--fix"})
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	assert.Error(t, cmd.Execute())
+}
@@ -1,6 +1,8 @@
 package gott
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	uuid "github.com/nu7hatch/gouuid"
@@ -26,25 +28,45 @@ type Interval struct {
 	Status     string
 }
 
-func (i *Interval) GetDuration() time.Duration {
+// GetDuration returns the tracked duration. Running intervals are measured
+// until now.
+func (i *Interval) GetDuration(now time.Time) time.Duration {
 	// completely the same. duration only
 	if i.End.Equal(i.Begin) {
 		return i.Duration
 	}
 	if i.End.IsZero() {
-		return time.Now().Sub(i.Begin)
+		return now.Sub(i.Begin)
 	}
 	return i.End.Sub(i.Begin)
 }
 
-func (i *Interval) Stop() {
-	i.End = time.Now()
+// Validate checks the interval on its own. Overlaps with other intervals are
+// checked by the commands, as they may be intended.
+func (i *Interval) Validate() error {
+	if i.ID == "" {
+		return errors.New("interval has no id")
+	}
+	if !i.End.IsZero() && i.End.Before(i.Begin) {
+		return fmt.Errorf("interval %s ends before it begins", i.ID)
+	}
+	if i.Status == StatusStarted && !i.End.IsZero() {
+		return fmt.Errorf("interval %s is %s but has an end", i.ID, i.Status)
+	}
+	if i.Status == StatusEnded && i.End.IsZero() {
+		return fmt.Errorf("interval %s is %s but has no end", i.ID, i.Status)
+	}
+	return nil
+}
+
+func (i *Interval) Stop(end time.Time) {
+	i.End = end
 	i.Status = StatusEnded
 }
 
 func NewInterval(raw []string) (interval Interval) {
 	id, _ := uuid.NewV4()
 	interval.ID = id.String()
-	lexInterval(raw, &interval)
+	lexInterval(raw, &interval, nil)
 	return interval
 }
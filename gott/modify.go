@@ -0,0 +1,206 @@
+package gott
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TagRemovePrefix removes a tag in modifications like -tag.
+const TagRemovePrefix = "-"
+
+// Modification is a partial change of intervals. Fields which are not set
+// are kept.
+type Modification struct {
+	Project    *string
+	Ref        *string
+	Annotation *string
+	AddTags    []string
+	RemoveTags []string
+	// UDA are the udas to set, nil values remove them
+	UDA   map[string]interface{}
+	Begin time.Time
+	End   time.Time
+}
+
+// lexModification lexes the modification args like lexInterval does. proj:,
+// ref: and declared udas without a value clear them, -tag removes the tag and
+// all other words replace the annotation.
+func lexModification(args []string, udas UDAs) (Modification, error) {
+	var m Modification
+	var words []string
+	for _, part := range args {
+		if tag := strings.TrimPrefix(part, TagPrefix); tag != part && tag != "" {
+			m.AddTags = append(m.AddTags, tag)
+			continue
+		}
+		if tag := strings.TrimPrefix(part, TagRemovePrefix); tag != part && tag != "" {
+			m.RemoveTags = append(m.RemoveTags, tag)
+			continue
+		}
+		if proj := strings.TrimPrefix(part, ProjectPrefixShort); proj != part {
+			m.Project = &proj
+			continue
+		}
+		if proj := strings.TrimPrefix(part, ProjectPrefix); proj != part {
+			m.Project = &proj
+			continue
+		}
+		if ref := strings.TrimPrefix(part, RefPrefix); ref != part {
+			m.Ref = &ref
+			continue
+		}
+		if uda, text, found := udas.lex(part); found {
+			if m.UDA == nil {
+				m.UDA = make(map[string]interface{})
+			}
+			m.UDA[uda.Name] = nil
+			if text != "" {
+				value, err := uda.Parse(text)
+				if err != nil {
+					return m, err
+				}
+				m.UDA[uda.Name] = value
+			}
+			continue
+		}
+		words = append(words, part)
+	}
+	if len(words) > 0 {
+		annotation := strings.Join(words, " ")
+		m.Annotation = &annotation
+	}
+	return m, nil
+}
+
+// Empty reports whether the modification changes nothing.
+func (m Modification) Empty() bool {
+	return m.Project == nil && m.Ref == nil && m.Annotation == nil &&
+		len(m.AddTags) == 0 && len(m.RemoveTags) == 0 && len(m.UDA) == 0 && m.Begin.IsZero() && m.End.IsZero()
+}
+
+// applyTo changes the interval and regenerates its raw text. Setting the
+// begin or end of a duration only interval keeps its duration.
+func (m Modification) applyTo(i *Interval) {
+	if m.Project != nil {
+		i.Project = *m.Project
+	}
+	if m.Ref != nil {
+		i.Ref = *m.Ref
+	}
+	if m.Annotation != nil {
+		i.Annotation = *m.Annotation
+	}
+	for _, tag := range m.AddTags {
+		if !containsString(i.Tags, tag) {
+			i.Tags = append(i.Tags, tag)
+		}
+	}
+	if len(m.RemoveTags) > 0 {
+		var tags []string
+		for _, tag := range i.Tags {
+			if !containsString(m.RemoveTags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		i.Tags = tags
+	}
+	if len(m.UDA) > 0 {
+		uda := make(map[string]interface{}, len(i.UDA))
+		for name, value := range i.UDA {
+			uda[name] = value
+		}
+		for name, value := range m.UDA {
+			if value == nil {
+				delete(uda, name)
+			} else {
+				uda[name] = value
+			}
+		}
+		i.UDA = nil
+		if len(uda) > 0 {
+			i.UDA = uda
+		}
+	}
+
+	if !m.Begin.IsZero() || !m.End.IsZero() {
+		durationOnly := i.End.Equal(i.Begin)
+		switch {
+		case !m.Begin.IsZero() && !m.End.IsZero():
+			i.Begin, i.End = m.Begin, m.End
+		case !m.Begin.IsZero() && durationOnly:
+			i.Begin, i.End = m.Begin, m.Begin.Add(i.Duration)
+		case !m.Begin.IsZero():
+			i.Begin = m.Begin
+		case durationOnly:
+			i.Begin, i.End = m.End.Add(-i.Duration), m.End
+		default:
+			i.End = m.End
+		}
+		if durationOnly {
+			i.Duration = 0
+		}
+		i.Status = statusOf(i)
+	}
+	i.Raw = formatRaw(i)
+}
+
+// formatRaw formats the interval like it is entered on the command line, so
+// lexing the raw text with the declared udas results in the same interval.
+func formatRaw(i *Interval) string {
+	var parts []string
+	if i.Annotation != "" {
+		parts = append(parts, i.Annotation)
+	}
+	if i.Project != "" {
+		parts = append(parts, ProjectPrefixShort+i.Project)
+	}
+	for _, tag := range i.Tags {
+		parts = append(parts, TagPrefix+tag)
+	}
+	if i.Ref != "" {
+		parts = append(parts, RefPrefix+i.Ref)
+	}
+	for _, name := range sortedUDANames(i) {
+		parts = append(parts, name+UDASep+fmtUDAValue(i.UDA[name]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// describeChanges lists the differences between the intervals before and
+// after, one per line.
+func (a *App) describeChanges(before, after *Interval) []string {
+	var changes []string
+	change := func(field, from, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", field, from, to))
+		}
+	}
+	fmtTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return a.local(t).Format(datetimeFormat)
+	}
+	change("begin", fmtTime(before.Begin), fmtTime(after.Begin))
+	change("end", fmtTime(before.End), fmtTime(after.End))
+	if before.Duration != after.Duration {
+		change("duration", before.Duration.String(), after.Duration.String())
+	}
+	change("project", before.Project, after.Project)
+	change("tags", strings.Join(before.Tags, ", "), strings.Join(after.Tags, ", "))
+	change("ref", before.Ref, after.Ref)
+	change("annotation", before.Annotation, after.Annotation)
+	change("uda", fmtUDA(before.UDA), fmtUDA(after.UDA))
+	return changes
+}
+
+// fmtUDA formats the udas as json with sorted keys.
+func fmtUDA(uda map[string]interface{}) string {
+	if len(uda) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(uda)
+	return string(data)
+}
@@ -0,0 +1,62 @@
+package gott
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModification(t *testing.T) {
+	begin := time.Date(2022, 1, 14, 9, 0, 0, 0, time.UTC)
+	i := &Interval{Begin: begin, End: begin.Add(time.Hour), Project: "gott", Tags: []string{"draft", "docs"}, Ref: "ID-1", Annotation: "writing"}
+
+	m, err := lexModification([]string{"proj:gott.docs", "+billable", "-draft", "ref:", "writing", "docs"}, nil)
+	assert.NoError(t, err)
+	assert.False(t, m.Empty())
+	m.applyTo(i)
+	assert.Equal(t, "gott.docs", i.Project)
+	assert.Equal(t, []string{"docs", "billable"}, i.Tags)
+	assert.Equal(t, "", i.Ref)
+	assert.Equal(t, "writing docs", i.Annotation)
+	assert.Equal(t, "writing docs proj:gott.docs +docs +billable", i.Raw)
+
+	// the raw text lexes to the same interval
+	relexed := &Interval{}
+	lexInterval(strings.Split(i.Raw, " "), relexed, nil)
+	assert.Equal(t, i.Annotation, relexed.Annotation)
+	assert.Equal(t, i.Project, relexed.Project)
+	assert.Equal(t, i.Tags, relexed.Tags)
+
+	m, _ = lexModification(nil, nil)
+	assert.True(t, m.Empty())
+}
+
+func TestModificationTimes(t *testing.T) {
+	day := time.Date(2022, 1, 14, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	i := &Interval{Begin: at(9, 0), End: at(10, 0), Status: StatusEnded}
+	Modification{End: at(10, 30)}.applyTo(i)
+	assert.Equal(t, at(9, 0), i.Begin)
+	assert.Equal(t, at(10, 30), i.End)
+
+	// duration only intervals keep their duration
+	i = &Interval{Begin: day, End: day, Duration: 2 * time.Hour}
+	Modification{Begin: at(13, 0)}.applyTo(i)
+	assert.Equal(t, at(15, 0), i.End)
+	assert.Equal(t, time.Duration(0), i.Duration)
+	assert.Equal(t, StatusEnded, i.Status)
+
+	i = &Interval{Begin: day, End: day, Duration: 2 * time.Hour}
+	Modification{End: at(13, 0)}.applyTo(i)
+	assert.Equal(t, at(11, 0), i.Begin)
+
+	// setting the end stops a running interval
+	i = &Interval{Begin: at(9, 0), Status: StatusStarted}
+	Modification{End: at(12, 0)}.applyTo(i)
+	assert.Equal(t, StatusEnded, i.Status)
+}
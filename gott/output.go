@@ -1,8 +1,11 @@
 package gott
 
 import (
+	"bufio"
 	"fmt"
+	"os"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/cheynewallace/tabby"
@@ -10,39 +13,44 @@ import (
 
 func DaySumLine(t *tabby.Tabby, dur time.Duration) {
 	if dur > 0 {
-		t.AddLine("", "", "", "day =", fmtDuration(dur), "", "", "")
+		t.AddLine("", "", "", "", "day =", fmtDuration(dur), "", "", "")
 	}
 }
 
 func WeekSumLine(t *tabby.Tabby, dur time.Duration) {
 	if dur > 0 {
-		t.AddLine("", "", "wk =", "", fmtDuration(dur), "", "", "")
+		t.AddLine("", "", "", "wk =", "", fmtDuration(dur), "", "", "")
 	}
 }
-func PrintStatus(interval *Interval) {
 
-	fmt.Printf("tracking %s", interval.Annotation)
+func (a *App) PrintStatus(interval *Interval) {
+
+	fmt.Fprintf(a.Out, "tracking %s", interval.Annotation)
 	if interval.Project != "" {
-		fmt.Printf(" -- proj:%s", interval.Project)
+		fmt.Fprintf(a.Out, " -- proj:%s", interval.Project)
 	}
 	if len(interval.Tags) > 0 {
-		fmt.Printf(" -- %s", strings.Join(interval.Tags, ", "))
+		fmt.Fprintf(a.Out, " -- %s", strings.Join(interval.Tags, ", "))
 	}
 	if interval.Ref != "" {
-		fmt.Printf(" -- ref:%s", interval.Ref)
+		fmt.Fprintf(a.Out, " -- ref:%s", interval.Ref)
+	}
+	for _, name := range sortedUDANames(interval) {
+		fmt.Fprintf(a.Out, " -- %s%s%s", name, UDASep, fmtUDAValue(interval.UDA[name]))
 	}
-	fmt.Printf("\n")
+	fmt.Fprintf(a.Out, "\n")
 
-	curDiff := time.Now().Sub(interval.Begin)
+	now := a.clock().Now()
+	curDiff := interval.GetDuration(now)
 	var todayDur time.Duration
-	intervals, _ := database.Filter([]string{KeyToday})
+	intervals, _ := a.Database.Filter([]string{KeyToday})
 	for _, i := range intervals {
-		todayDur += i.GetDuration()
+		todayDur += i.GetDuration(now)
 	}
-	t := tabby.New()
-	t.AddLine("\t", "Started", interval.Begin.Format(datetimeFormatShort))
+	t := tabby.NewCustom(tabwriter.NewWriter(a.Out, 0, 0, 2, ' ', 0))
+	t.AddLine("\t", "Started", a.local(interval.Begin).Format(datetimeFormatShort))
 	if !interval.End.IsZero() {
-		t.AddLine("\t", "Stopped", interval.Begin.Format(datetimeFormatShort))
+		t.AddLine("\t", "Stopped", a.local(interval.End).Format(datetimeFormatShort))
 	}
 	t.AddLine("\t", "Current (mins)", fmtDuration(curDiff))
 	t.AddLine("\t", "Total   (today)", fmtDuration(todayDur))
@@ -50,12 +58,47 @@ func PrintStatus(interval *Interval) {
 
 }
 
-func PrintRunningStatus() {
-	if current, found := database.GetCurrent(); !found {
-		fmt.Println("<< no tracking in progress >>")
+func (a *App) PrintRunningStatus() {
+	if current, found := a.Database.GetCurrent(); !found {
+		fmt.Fprintln(a.Out, "<< no tracking in progress >>")
 	} else {
-		PrintStatus(current)
+		a.PrintStatus(current)
+	}
+}
+
+// printOverlaps prints the intervals overlapping interval to stderr.
+func (a *App) printOverlaps(level string, interval *Interval, overlapping []*Interval) {
+	fmt.Fprintf(os.Stderr, "%s: %s overlaps\n", level, a.fmtInterval(interval))
+	for _, i := range overlapping {
+		fmt.Fprintf(os.Stderr, "  %s\n", a.fmtInterval(i))
+	}
+}
+
+// fmtInterval formats the interval on one line like 2022-01-14 09:30-11:15
+// annotation.
+func (a *App) fmtInterval(i *Interval) string {
+	begin := a.local(i.Begin)
+	var when string
+	switch {
+	case i.End.Equal(i.Begin):
+		when = begin.Format(dateFormat) + " " + fmtDuration(i.Duration)
+	case i.End.IsZero():
+		when = begin.Format(dateFormat+" "+timeFormat) + "-tracking..."
+	default:
+		when = begin.Format(dateFormat+" "+timeFormat) + "-" + a.local(i.End).Format(timeFormat)
+	}
+	return strings.TrimSpace(when + " " + i.Annotation)
+}
+
+// confirm asks the question and reports whether it was answered with yes.
+func (a *App) confirm(question string) bool {
+	fmt.Fprintf(a.Out, "%s (yes/no) ", question)
+	if a.inReader == nil || a.inSource != a.In {
+		a.inSource, a.inReader = a.In, bufio.NewReader(a.In)
 	}
+	answer, _ := a.inReader.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "yes" || answer == "y"
 }
 
 func fmtDuration(d time.Duration) string {
@@ -0,0 +1,131 @@
+package gott
+
+import (
+	"fmt"
+	"time"
+)
+
+// span returns the time covered by the interval. Duration only intervals have
+// no time of day, ok is false for them. Running intervals last until now.
+func span(i *Interval, now time.Time) (begin, end time.Time, ok bool) {
+	if i.Begin.IsZero() || i.End.Equal(i.Begin) {
+		return begin, end, false
+	}
+	end = i.End
+	if end.IsZero() {
+		end = now
+	}
+	return i.Begin, end, true
+}
+
+// overlaps reports whether the intervals a and b share any time.
+func overlaps(a, b *Interval, now time.Time) bool {
+	aBegin, aEnd, aOk := span(a, now)
+	bBegin, bEnd, bOk := span(b, now)
+	if !aOk || !bOk {
+		return false
+	}
+	return aBegin.Before(bEnd) && bBegin.Before(aEnd)
+}
+
+// checkStop validates stopping the current interval at the time at. It
+// returns the intervals the stopped interval would overlap.
+func checkStop(db Database, at, now time.Time) ([]*Interval, error) {
+	cur, found := db.GetCurrent()
+	if !found {
+		return nil, nil
+	}
+	if !at.After(cur.Begin) {
+		return nil, fmt.Errorf("the current interval began at %s, after %s", cur.Begin.Format(datetimeFormatShort), at.Format(datetimeFormatShort))
+	}
+	stopped := copyInterval(cur)
+	stopped.Stop(at)
+	return findOverlaps(db, stopped, now)
+}
+
+// checkStart validates starting interval at the time at, which stops the
+// current interval at the same time. It returns the intervals the started or
+// stopped interval would overlap.
+func checkStart(db Database, interval Interval, at, now time.Time) ([]*Interval, error) {
+	result, err := checkStop(db, at, now)
+	if err != nil {
+		return nil, err
+	}
+	interval.Begin = at
+	overlapping, err := findOverlaps(db, &interval, now)
+	if err != nil {
+		return nil, err
+	}
+	cur, found := db.GetCurrent()
+	for _, i := range overlapping {
+		// the current interval is stopped when the new one begins
+		if found && i.ID == cur.ID {
+			continue
+		}
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+// findOverlaps returns the intervals of db overlapping interval, except
+// interval itself.
+func findOverlaps(db Database, interval *Interval, now time.Time) ([]*Interval, error) {
+	begin, end, ok := span(interval, now)
+	if !ok {
+		return nil, nil
+	}
+	intervals, err := db.Overlapping(begin, end)
+	if err != nil {
+		return nil, err
+	}
+	var result []*Interval
+	for _, i := range intervals {
+		if i.ID != interval.ID && overlaps(i, interval, now) {
+			result = append(result, i)
+		}
+	}
+	return result, nil
+}
+
+// findBatchOverlaps returns the intervals overlapping the changed intervals
+// of batch by their id, as they are once the whole batch is applied. Other
+// intervals of the batch are compared in their changed state.
+func findBatchOverlaps(db Database, batch []*Interval, now time.Time) (map[string][]*Interval, error) {
+	// only the time the batch covers is loaded
+	var begin, end time.Time
+	changed := map[string]bool{}
+	for _, i := range batch {
+		changed[i.ID] = true
+		if b, e, ok := span(i, now); ok {
+			if begin.IsZero() || b.Before(begin) {
+				begin = b
+			}
+			if e.After(end) {
+				end = e
+			}
+		}
+	}
+	if begin.IsZero() {
+		return nil, nil
+	}
+	intervals, err := db.Overlapping(begin, end)
+	if err != nil {
+		return nil, err
+	}
+	after := append([]*Interval{}, batch...)
+	for _, i := range intervals {
+		if !changed[i.ID] {
+			after = append(after, i)
+		}
+	}
+
+	result := map[string][]*Interval{}
+	for _, b := range batch {
+		for _, i := range after {
+			if i.ID != b.ID && overlaps(i, b, now) {
+				result[b.ID] = append(result[b.ID], i)
+			}
+		}
+	}
+	return result, nil
+}
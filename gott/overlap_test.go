@@ -0,0 +1,91 @@
+package gott
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindOverlaps(t *testing.T) {
+	now := time.Date(2022, 1, 14, 12, 0, 0, 0, time.UTC)
+	db := NewDatabaseJson("", Calendar{Clock: FixedClock{Time: now}})
+
+	add := func(annotation string, begin, end time.Time) {
+		i := NewInterval([]string{annotation})
+		i.Begin = begin
+		i.End = end
+		i.Duration = end.Sub(begin)
+		if begin.Equal(end) {
+			i.Duration = time.Hour
+		}
+		db.Append(i)
+	}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2022, 1, 14, hour, minute, 0, 0, time.UTC)
+	}
+	add("morning", at(9, 0), at(10, 0))
+	add("duration only", at(0, 0), at(0, 0))
+	add("running", at(11, 0), time.Time{})
+
+	annotations := func(begin, end time.Time) []string {
+		i := &Interval{ID: "new", Begin: begin, End: end}
+		overlapping, err := findOverlaps(db, i, now)
+		assert.NoError(t, err)
+		var result []string
+		for _, o := range overlapping {
+			result = append(result, o.Annotation)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"morning"}, annotations(at(9, 30), at(10, 30)))
+	assert.Empty(t, annotations(at(10, 0), at(11, 0)))
+	assert.Equal(t, []string{"running"}, annotations(at(11, 30), at(11, 45)))
+	assert.Empty(t, annotations(at(12, 0), at(13, 0)))
+	assert.Equal(t, []string{"morning", "running"}, annotations(at(8, 0), at(13, 0)))
+	assert.Empty(t, annotations(at(8, 0), at(8, 0)))
+}
+
+func TestFindBatchOverlaps(t *testing.T) {
+	now := time.Date(2022, 1, 14, 18, 0, 0, 0, time.UTC)
+	db := NewDatabaseJson("", Calendar{Clock: FixedClock{Time: now}})
+	at := func(hour, minute int) time.Time {
+		return time.Date(2022, 1, 14, hour, minute, 0, 0, time.UTC)
+	}
+	var batch []*Interval
+	for _, hour := range []int{8, 9, 10} {
+		i := NewInterval([]string{"work"})
+		i.Begin, i.End, i.Status = at(hour, 0), at(hour, 30), StatusEnded
+		assert.NoError(t, db.Append(i))
+		if hour < 10 {
+			moved := copyInterval(&i)
+			moved.End = at(11, 0)
+			batch = append(batch, moved)
+		}
+	}
+
+	// both moved intervals overlap the one at 10:00 and each other
+	overlapping, err := findBatchOverlaps(db, batch, now)
+	assert.NoError(t, err)
+	assert.Len(t, overlapping[batch[0].ID], 2)
+	assert.Len(t, overlapping[batch[1].ID], 2)
+
+	// moved next to each other they do not overlap
+	batch[0].End = at(9, 0)
+	batch[1].End = at(10, 0)
+	overlapping, err = findBatchOverlaps(db, batch, now)
+	assert.NoError(t, err)
+	assert.Empty(t, overlapping[batch[0].ID])
+	assert.Empty(t, overlapping[batch[1].ID])
+
+	// the stored interval at 9:00 is moved too, only its new times count
+	batch[0].End = at(9, 15)
+	overlapping, err = findBatchOverlaps(db, batch[:1], now)
+	assert.NoError(t, err)
+	assert.Len(t, overlapping[batch[0].ID], 1)
+	overlapping, err = findBatchOverlaps(db, batch, now)
+	assert.NoError(t, err)
+	assert.Len(t, overlapping[batch[0].ID], 1)
+	assert.Equal(t, batch[1].ID, overlapping[batch[0].ID][0].ID)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/satishvis/gott/gott/dateexpr"
 )
 
 const (
@@ -11,34 +13,140 @@ const (
 	ProjectPrefixShort = "proj:"
 	RefPrefix          = "ref:"
 	TagPrefix          = "+"
+
+	TrackTimeSep = "-"
+	TrackFor     = "for"
+	AgoSuffix    = "ago"
 )
 
-func lexTrack(args []string, interval *Interval) error {
-	switch args[0] {
-	case KeyToday:
-		interval.Begin = time.Now().Truncate(24 * time.Hour)
-		interval.End = time.Now().Truncate(24 * time.Hour)
-	case KeyYesterday:
-		interval.Begin = time.Now().Truncate(24*time.Hour).AddDate(0, 0, -1)
-		interval.End = time.Now().Truncate(24*time.Hour).AddDate(0, 0, -1)
-	default:
-		if startDate, err := time.Parse(dateFormat, args[0]); err != nil {
-			return fmt.Errorf("ERROR: Invalid date format. %s", err.Error())
-		} else {
-			interval.Begin = startDate
-			interval.End = startDate
+// lexAt returns the time given by the --at flag value or by DURATION ago at
+// the start of args and the remaining args. at is HH:MM on the current
+// working day or YYYY-MM-DD HH:MM. It defaults to now.
+func lexAt(at string, args []string, cal Calendar) (time.Time, []string, error) {
+	now := cal.Now()
+	ago := len(args) > 1 && args[1] == AgoSuffix
+	var duration time.Duration
+	if ago {
+		var err error
+		if duration, err = time.ParseDuration(args[0]); err != nil || duration < 0 {
+			ago = false
 		}
 	}
 
-	if duration, err := time.ParseDuration(args[1]); err != nil {
-		return fmt.Errorf("ERROR: Invalid duration format. %s", err.Error())
-	} else {
+	switch {
+	case ago && at != "":
+		return now, args, fmt.Errorf("ERROR: use either --at or %s %s", args[0], args[1])
+	case ago:
+		return now.Add(-duration), args[2:], nil
+	case at == "":
+		return now, args, nil
+	}
+
+	t, err := parseTimeOn(at, cal.Day(now), cal)
+	if err != nil {
+		return now, args, err
+	}
+	return t, args, nil
+}
+
+// parseTimeOn parses HH:MM on the working day of day or YYYY-MM-DD HH:MM.
+func parseTimeOn(value string, day time.Time, cal Calendar) (time.Time, error) {
+	if t, err := time.Parse(timeFormat, value); err == nil {
+		return cal.At(day, t.Hour(), t.Minute()), nil
+	}
+	if t, err := time.ParseInLocation(dateFormat+" "+timeFormat, value, day.Location()); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("ERROR: Invalid time %s. use HH:MM or YYYY-MM-DD HH:MM", value)
+}
+
+// lexTrack lexes the DATE TIMES ANNOTATION arguments of track. DATE is a
+// date expression of a single day, it may span several args like 3d ago.
+// TIMES is one of
+//
+//	3h              a duration only interval
+//	09:30-11:15     an interval from 09:30 until 11:15
+//	14:00 for 45m   an interval from 14:00 until 14:45
+//
+// Duration only intervals begin and end at the start of their working day.
+// Times are on the working day of DATE, so times before the day boundary are
+// on the next date. An end before the begin is on the next date as well.
+func lexTrack(args []string, interval *Interval, cal Calendar, udas UDAs) error {
+	r, n, err := dateexpr.Parse(args, cal.Day(cal.Now()))
+	if err != nil {
+		return fmt.Errorf("ERROR: Invalid date format. %s", err.Error())
+	}
+	if n == 0 {
+		return fmt.Errorf("ERROR: Invalid date format. %s is not a date", args[0])
+	}
+	if !r.Single() {
+		return fmt.Errorf("ERROR: Invalid date format. %s is more than one day", strings.Join(args[:n], " "))
+	}
+	day := r.From
+	args = args[n:]
+
+	if len(args) == 0 {
+		return fmt.Errorf("ERROR: Missing duration")
+	}
+	switch {
+	case strings.Contains(args[0], ":") && strings.Contains(args[0], TrackTimeSep):
+		idx := strings.Index(args[0], TrackTimeSep)
+		begin, err := parseTimeOfDay(args[0][:idx], day, cal)
+		if err != nil {
+			return err
+		}
+		end, err := parseTimeOfDay(args[0][idx+len(TrackTimeSep):], day, cal)
+		if err != nil {
+			return err
+		}
+		if !end.After(begin) {
+			end = cal.At(startOfDay(end).AddDate(0, 0, 1), end.Hour(), end.Minute())
+		}
+		interval.Begin = begin
+		interval.End = end
+		args = args[1:]
+	case len(args) > 2 && args[1] == TrackFor:
+		begin, err := parseTimeOfDay(args[0], day, cal)
+		if err != nil {
+			return err
+		}
+		duration, err := time.ParseDuration(args[2])
+		if err != nil {
+			return fmt.Errorf("ERROR: Invalid duration format. %s", err.Error())
+		}
+		if duration <= 0 {
+			return fmt.Errorf("ERROR: Duration %s must be positive", args[2])
+		}
+		interval.Begin = begin
+		interval.End = begin.Add(duration)
+		args = args[3:]
+	default:
+		duration, err := time.ParseDuration(args[0])
+		if err != nil {
+			return fmt.Errorf("ERROR: Invalid duration format. %s", err.Error())
+		}
+		interval.Begin = cal.DayStart(day)
+		interval.End = interval.Begin
 		interval.Duration = duration
+		args = args[1:]
 	}
-	return nil
+	interval.Status = StatusEnded
+
+	return lexInterval(args, interval, udas)
+}
+
+// parseTimeOfDay parses HH:MM on the working day of day.
+func parseTimeOfDay(value string, day time.Time, cal Calendar) (time.Time, error) {
+	t, err := time.Parse(timeFormat, value)
+	if err != nil {
+		return t, fmt.Errorf("ERROR: Invalid time format %s. use HH:MM", value)
+	}
+	return cal.At(day, t.Hour(), t.Minute()), nil
 }
 
-func lexInterval(args []string, interval *Interval) {
+// lexInterval lexes the annotation, project, tags, ref and the declared udas
+// like estimate:2h from args.
+func lexInterval(args []string, interval *Interval, udas UDAs) error {
 
 	interval.Raw = strings.Join(args, " ")
 	// reset if relexing for annotate
@@ -69,9 +177,16 @@ func lexInterval(args []string, interval *Interval) {
 			continue
 		}
 
-		// TODO: uda
+		if uda, text, found := udas.lex(part); found {
+			value, err := uda.Parse(text)
+			if err != nil {
+				return err
+			}
+			setUDA(interval, uda.Name, value)
+			continue
+		}
 
 		interval.Annotation = strings.Trim(strings.Join([]string{interval.Annotation, part}, " "), " ")
 	}
-
+	return nil
 }
@@ -0,0 +1,82 @@
+package gott
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageLexer(t *testing.T) {
+
+	input := []string{"hello", "world", "+tag01", "+tag02", "proj:project01", "ref:externalReference", "!"}
+
+	interval := &Interval{}
+	lexInterval(input, interval, nil)
+
+	assert.Equal(t, "hello world !", interval.Annotation)
+	assert.Equal(t, []string{"tag01", "tag02"}, interval.Tags)
+	assert.Equal(t, "project01", interval.Project)
+	assert.Equal(t, "externalReference", interval.Ref)
+	assert.Equal(t, strings.Join(input, " "), interval.Raw)
+
+}
+
+func TestTrackLexerUsesNow(t *testing.T) {
+	now := time.Date(2022, 1, 14, 22, 44, 0, 0, time.UTC)
+
+	interval := &Interval{}
+	assert.NoError(t, lexTrack([]string{KeyYesterday, "3h"}, interval, Calendar{Clock: FixedClock{Time: now}}, nil))
+	assert.Equal(t, time.Date(2022, 1, 13, 0, 0, 0, 0, time.UTC), interval.Begin)
+	assert.Equal(t, 3*time.Hour, interval.GetDuration(now))
+}
+
+func TestTrackLexerDateExpressions(t *testing.T) {
+	// friday
+	now := time.Date(2022, 1, 14, 22, 44, 0, 0, time.UTC)
+	cal := Calendar{Clock: FixedClock{Time: now}}
+
+	interval := &Interval{}
+	assert.NoError(t, lexTrack([]string{"3d", "ago", "2h", "+dev", "review"}, interval, cal, nil))
+	assert.Equal(t, time.Date(2022, 1, 11, 0, 0, 0, 0, time.UTC), interval.Begin)
+	assert.Equal(t, 2*time.Hour, interval.Duration)
+	assert.Equal(t, []string{"dev"}, interval.Tags)
+	assert.Equal(t, "review", interval.Annotation)
+
+	interval = &Interval{}
+	assert.NoError(t, lexTrack([]string{"monday", "1h"}, interval, cal, nil))
+	assert.Equal(t, time.Date(2022, 1, 10, 0, 0, 0, 0, time.UTC), interval.Begin)
+
+	assert.Error(t, lexTrack([]string{":lastweek", "1h"}, &Interval{}, cal, nil))
+	assert.Error(t, lexTrack([]string{"someday", "1h"}, &Interval{}, cal, nil))
+	assert.Error(t, lexTrack([]string{"3d", "ago"}, &Interval{}, cal, nil))
+}
+
+func TestTrackLexerTimes(t *testing.T) {
+	now := time.Date(2022, 1, 14, 22, 44, 0, 0, time.UTC)
+	cal := Calendar{Clock: FixedClock{Time: now}}
+
+	interval := &Interval{}
+	assert.NoError(t, lexTrack([]string{"2022-01-10", "09:30-11:15", "meeting"}, interval, cal, nil))
+	assert.Equal(t, time.Date(2022, 1, 10, 9, 30, 0, 0, time.UTC), interval.Begin)
+	assert.Equal(t, time.Date(2022, 1, 10, 11, 15, 0, 0, time.UTC), interval.End)
+	assert.Equal(t, time.Duration(0), interval.Duration)
+	assert.Equal(t, "meeting", interval.Annotation)
+
+	interval = &Interval{}
+	assert.NoError(t, lexTrack([]string{KeyYesterday, "14:00", "for", "45m", "+call"}, interval, cal, nil))
+	assert.Equal(t, time.Date(2022, 1, 13, 14, 0, 0, 0, time.UTC), interval.Begin)
+	assert.Equal(t, time.Date(2022, 1, 13, 14, 45, 0, 0, time.UTC), interval.End)
+	assert.Equal(t, []string{"call"}, interval.Tags)
+	assert.Equal(t, 45*time.Minute, interval.GetDuration(now))
+
+	// past midnight
+	interval = &Interval{}
+	assert.NoError(t, lexTrack([]string{"2022-01-10", "23:00-01:30"}, interval, cal, nil))
+	assert.Equal(t, time.Date(2022, 1, 11, 1, 30, 0, 0, time.UTC), interval.End)
+
+	assert.Error(t, lexTrack([]string{"2022-01-10", "9:3x-11:15"}, &Interval{}, cal, nil))
+	assert.Error(t, lexTrack([]string{"2022-01-10", "09:00", "for", "soon"}, &Interval{}, cal, nil))
+	assert.Error(t, lexTrack([]string{"2022-01-10", "09:00", "for", "-1h"}, &Interval{}, cal, nil))
+}
@@ -0,0 +1,121 @@
+package gott
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SchemaVersion is the version of the json database file written by this
+// version of gott.
+const SchemaVersion = 2
+
+// jsonMigration upgrades the json database document from one schema version
+// to the next. It works on the untyped document, so it does not depend on the
+// current Interval struct.
+type jsonMigration struct {
+	description string
+	migrate     func(doc map[string]interface{}, cal Calendar) error
+}
+
+// jsonMigrations[v] migrates version v to version v+1. Files written before
+// the schema version was introduced are version 0.
+var jsonMigrations = []jsonMigration{
+	{
+		description: "add schema version",
+		migrate:     func(doc map[string]interface{}, cal Calendar) error { return nil },
+	},
+	{
+		description: migrateDurationOnlyDescription,
+		migrate:     migrateJsonDurationOnly,
+	},
+}
+
+const migrateDurationOnlyDescription = "move intervals with only a duration to the start of their working day"
+
+// anchorDurationOnly returns where an interval with only a duration stored
+// at begin belongs: the start of the working day on its date. Before schema
+// version 2 they were stored at midnight UTC, which is on the previous day
+// west of UTC and before the day boundary. Other times keep the date they
+// have in their own zone.
+func anchorDurationOnly(begin time.Time, cal Calendar) time.Time {
+	date := begin
+	if utc := begin.UTC(); utc.Equal(startOfDay(utc)) {
+		date = utc
+	}
+	y, m, d := date.Date()
+	return cal.DayStart(time.Date(y, m, d, 0, 0, 0, 0, cal.Now().Location()))
+}
+
+func migrateJsonDurationOnly(doc map[string]interface{}, cal Calendar) error {
+	intervals, _ := doc["Intervals"].([]interface{})
+	for n, value := range intervals {
+		interval, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("interval %d is not an object", n+1)
+		}
+		beginText, _ := interval["Begin"].(string)
+		endText, _ := interval["End"].(string)
+		begin, errBegin := time.Parse(time.RFC3339Nano, beginText)
+		end, errEnd := time.Parse(time.RFC3339Nano, endText)
+		if errBegin != nil || errEnd != nil || begin.IsZero() || !begin.Equal(end) {
+			continue
+		}
+		// begin equal to end without duration is a real point in time
+		if duration, _ := interval["Duration"].(float64); duration == 0 {
+			continue
+		}
+		anchored := anchorDurationOnly(begin, cal).Format(time.RFC3339Nano)
+		interval["Begin"], interval["End"] = anchored, anchored
+	}
+	return nil
+}
+
+// Migrator is implemented by databases which upgrade older files on Load.
+type Migrator interface {
+	// Migrated describes the migrations run on Load. They are written with
+	// the next Save.
+	Migrated() []string
+}
+
+// migrateJson upgrades the raw json database file to SchemaVersion and
+// returns the upgraded file and the description of every migration run.
+// Times are moved into the working days of cal.
+func migrateJson(data []byte, cal Calendar) ([]byte, []string, error) {
+	var header struct {
+		SchemaVersion int
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, nil, err
+	}
+	version := header.SchemaVersion
+	if version == SchemaVersion {
+		return data, nil, nil
+	}
+	if version > SchemaVersion {
+		return nil, nil, fmt.Errorf(
+			"database schema version %d is newer than the supported version %d. please update gott",
+			version, SchemaVersion,
+		)
+	}
+	if version < 0 {
+		return nil, nil, fmt.Errorf("invalid database schema version %d", version)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	var migrated []string
+	for ; version < SchemaVersion; version++ {
+		m := jsonMigrations[version]
+		if err := m.migrate(doc, cal); err != nil {
+			return nil, nil, fmt.Errorf("error migrating schema version %d to %d: %s", version, version+1, err.Error())
+		}
+		migrated = append(migrated, fmt.Sprintf("v%d -> v%d: %s", version, version+1, m.description))
+	}
+	doc["SchemaVersion"] = SchemaVersion
+
+	result, err := json.Marshal(doc)
+	return result, migrated, err
+}
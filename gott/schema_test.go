@@ -0,0 +1,111 @@
+package gott
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// loadFixture loads a copy of testdata/db_v<version>.json.
+func loadFixture(t *testing.T, version int) *DatabaseJson {
+	return loadFixtureIn(t, version, Calendar{Clock: SystemClock{}})
+}
+
+// loadFixtureIn loads a copy of testdata/db_v<version>.json with cal.
+func loadFixtureIn(t *testing.T, version int, cal Calendar) *DatabaseJson {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", fmt.Sprintf("db_v%d.json", version)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "db.json")
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	db := NewDatabaseJson(filename, cal)
+	assert.NoError(t, db.Load())
+	return db
+}
+
+func TestLoadEverySchemaVersion(t *testing.T) {
+	for version := 0; version <= SchemaVersion; version++ {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			db := loadFixture(t, version)
+
+			assert.Equal(t, SchemaVersion, db.SchemaVersion)
+			assert.Len(t, db.Migrated(), SchemaVersion-version)
+			assert.Equal(t, 2, db.Count())
+
+			cake, found := db.Get("0b6c2d1e-7b1a-4a8e-5d2c-9f3e4a5b6c7d")
+			if assert.True(t, found) {
+				assert.Equal(t, 3*time.Hour, cake.GetDuration(time.Now()))
+				assert.Equal(t, []string{"food"}, cake.Tags)
+				assert.Equal(t, "kitchen", cake.Project)
+				assert.Equal(t, "bake a cake", cake.Annotation)
+			}
+
+			current, found := db.GetCurrent()
+			if assert.True(t, found) {
+				assert.Equal(t, "gott.docs", current.Project)
+				assert.Equal(t, "ID-1337", current.Ref)
+				assert.True(t, current.End.IsZero())
+			}
+
+			// saved files are current
+			assert.NoError(t, db.Save())
+			saved := NewDatabaseJson(db.filename, Calendar{Clock: SystemClock{}})
+			assert.NoError(t, saved.Load())
+			assert.Empty(t, saved.Migrated())
+		})
+	}
+}
+
+func TestMigrateRejectsNewerVersion(t *testing.T) {
+	_, _, err := migrateJson([]byte(fmt.Sprintf(`{"SchemaVersion":%d}`, SchemaVersion+1)), Calendar{Clock: SystemClock{}})
+	assert.Error(t, err)
+}
+
+func TestMigrateDurationOnly(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	now := time.Date(2022, 1, 20, 12, 0, 0, 0, time.UTC)
+	for _, cal := range []Calendar{
+		{Clock: FixedClock{now}, DayBoundary: 4 * time.Hour},
+		{Clock: FixedClock{now.In(newYork)}},
+		{Clock: FixedClock{now.In(newYork)}, DayBoundary: 4 * time.Hour},
+	} {
+		loc := cal.Now().Location()
+		db := loadFixtureIn(t, 0, cal)
+		cake, found := db.Get("0b6c2d1e-7b1a-4a8e-5d2c-9f3e4a5b6c7d")
+		if !assert.True(t, found) {
+			continue
+		}
+		// stored at midnight UTC, it stays on its date
+		dayStart := cal.DayStart(time.Date(2022, 1, 14, 0, 0, 0, 0, loc))
+		assert.True(t, dayStart.Equal(cake.Begin), cake.Begin)
+		assert.True(t, dayStart.Equal(cake.End), cake.End)
+		intervals, err := db.Filter([]string{"2022-01-14", "+food"})
+		assert.NoError(t, err)
+		assert.Len(t, intervals, 1, loc.String())
+		intervals, err = db.Filter([]string{"2022-01-13", "+food"})
+		assert.NoError(t, err)
+		assert.Empty(t, intervals, loc.String())
+
+		// the migration keeps intervals at the start of their working day
+		data, _, err := migrateJson([]byte(`{"SchemaVersion":1,"Intervals":[{"Begin":"`+dayStart.Format(time.RFC3339)+
+			`","End":"`+dayStart.Format(time.RFC3339)+`","Duration":60}]}`), cal)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `"Begin":"`+dayStart.Format(time.RFC3339)+`"`)
+
+		// without a duration begin equal to end is a point in time
+		point := "2022-01-14T15:00:00Z"
+		data, _, err = migrateJson([]byte(`{"SchemaVersion":1,"Intervals":[{"Begin":"`+point+`","End":"`+point+`","Duration":0}]}`), cal)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `"Begin":"`+point+`"`)
+	}
+}
@@ -0,0 +1,377 @@
+package gott
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	uuid "github.com/nu7hatch/gouuid"
+)
+
+// Timewarrior stores intervals in monthly files like data/2022-01.data, one
+// per line:
+//
+//	inc 20220114T080000Z - 20220114T090000Z # tag "tag with spaces" # "annotation"
+//
+// The end is missing for the running interval. Without tags the annotation
+// follows "# #".
+const (
+	timewTimeFormat = "20060102T150405Z"
+	timewInc        = "inc"
+	timewEndSep     = "-"
+	timewTagSep     = "#"
+	timewDataDir    = "data"
+	timewDataGlob   = "[0-9][0-9][0-9][0-9]-[0-9][0-9].data"
+	timewFileFormat = "2006-01.data"
+	timewTagsFile   = "tags.data"
+	timewConfigFile = "timewarrior.cfg"
+)
+
+// timewInterval is an interval of timewarrior.
+type timewInterval struct {
+	Start      time.Time
+	End        time.Time
+	Tags       []string
+	Annotation string
+}
+
+// parseTimewLine parses a line of a timewarrior data file.
+func parseTimewLine(line string) (timewInterval, error) {
+	var t timewInterval
+	fields, err := splitEditLine(line)
+	if err != nil {
+		return t, err
+	}
+	if len(fields) < 2 || fields[0] != timewInc {
+		return t, fmt.Errorf("expected %s START [- END] [# TAGS] [# \"ANNOTATION\"]", timewInc)
+	}
+	if t.Start, err = time.Parse(timewTimeFormat, fields[1]); err != nil {
+		return t, fmt.Errorf("invalid start %s", fields[1])
+	}
+	fields = fields[2:]
+	if len(fields) > 1 && fields[0] == timewEndSep {
+		if t.End, err = time.Parse(timewTimeFormat, fields[1]); err != nil {
+			return t, fmt.Errorf("invalid end %s", fields[1])
+		}
+		fields = fields[2:]
+	}
+	if len(fields) == 0 {
+		return t, nil
+	}
+	if fields[0] != timewTagSep {
+		return t, fmt.Errorf("unexpected %s", fields[0])
+	}
+	fields = fields[1:]
+	for n, field := range fields {
+		if field == timewTagSep {
+			if len(fields[n+1:]) != 1 {
+				return t, errors.New("expected one quoted annotation after the tags")
+			}
+			t.Annotation = unquoteTimew(fields[n+1])
+			break
+		}
+		t.Tags = append(t.Tags, unquoteTimew(field))
+	}
+	return t, nil
+}
+
+// unquoteTimew removes the quotes of tags and annotations. timewarrior only
+// escapes quotes.
+func unquoteTimew(s string) string {
+	if len(s) > 1 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		return strings.ReplaceAll(s[1:len(s)-1], `\"`, `"`)
+	}
+	return s
+}
+
+// readTimewFile reads the intervals of a timewarrior data file. Errors
+// contain the line number.
+func readTimewFile(r io.Reader) ([]timewInterval, error) {
+	scanner := bufio.NewScanner(r)
+	var result []timewInterval
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		t, err := parseTimewLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err.Error())
+		}
+		result = append(result, t)
+	}
+	return result, scanner.Err()
+}
+
+// defaultTimewPath returns $TIMEWARRIORDB or ~/.timewarrior.
+func defaultTimewPath() string {
+	if db := os.Getenv("TIMEWARRIORDB"); db != "" {
+		return db
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".timewarrior")
+}
+
+// timewDataFiles returns the monthly data files of path sorted by month. path
+// is the timewarrior directory, its data directory or a data file.
+func timewDataFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	if data := filepath.Join(path, timewDataDir); isDir(data) {
+		path = data
+	}
+	files, err := filepath.Glob(filepath.Join(path, timewDataGlob))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no timewarrior data files in %s", path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// timewID returns the id of the imported interval. Timewarrior intervals do
+// not overlap, so the start identifies them and a second import finds them.
+func timewID(start time.Time) string {
+	id, _ := uuid.NewV5(uuid.NamespaceURL, []byte("timewarrior:"+start.UTC().Format(timewTimeFormat)))
+	return id.String()
+}
+
+// toInterval converts the timewarrior interval. Tags like proj:NAME,
+// project:NAME, ref:ID, +tag and declared udas like estimate:2h are
+// recognized, all other tags are kept as they are.
+func (t timewInterval) toInterval(udas UDAs) Interval {
+	i := Interval{
+		ID:         timewID(t.Start),
+		Begin:      t.Start,
+		End:        t.End,
+		Annotation: t.Annotation,
+	}
+	for _, tag := range t.Tags {
+		if proj := strings.TrimPrefix(tag, ProjectPrefixShort); proj != tag {
+			i.Project = proj
+			continue
+		}
+		if proj := strings.TrimPrefix(tag, ProjectPrefix); proj != tag {
+			i.Project = proj
+			continue
+		}
+		if ref := strings.TrimPrefix(tag, RefPrefix); ref != tag {
+			i.Ref = ref
+			continue
+		}
+		if uda, text, found := udas.lex(tag); found {
+			if value, err := uda.Parse(text); err == nil {
+				setUDA(&i, uda.Name, value)
+				continue
+			}
+		}
+		if plain := strings.TrimPrefix(tag, TagPrefix); plain != "" {
+			tag = plain
+		}
+		i.Tags = append(i.Tags, tag)
+	}
+	i.Status = statusOf(&i)
+	i.Raw = formatRaw(&i)
+	return i
+}
+
+// timewFromInterval converts the interval to timewarrior. The project, ref
+// and udas become tags like proj:NAME, so importing them again restores them.
+// Duration only intervals begin at the start of their day. Timewarrior keeps
+// tags sorted.
+func timewFromInterval(i *Interval) timewInterval {
+	t := timewInterval{
+		Start:      i.Begin.UTC().Truncate(time.Second),
+		Annotation: i.Annotation,
+	}
+	switch {
+	case i.End.Equal(i.Begin):
+		t.End = t.Start.Add(i.Duration)
+	case !i.End.IsZero():
+		t.End = i.End.UTC().Truncate(time.Second)
+	}
+	t.Tags = append(t.Tags, i.Tags...)
+	if i.Project != "" {
+		t.Tags = append(t.Tags, ProjectPrefixShort+i.Project)
+	}
+	if i.Ref != "" {
+		t.Tags = append(t.Tags, RefPrefix+i.Ref)
+	}
+	for _, name := range sortedUDANames(i) {
+		t.Tags = append(t.Tags, name+UDASep+fmtUDAValue(i.UDA[name]))
+	}
+	sort.Strings(t.Tags)
+	return t
+}
+
+// findTimewInterval returns the interval of db with the start and end of t,
+// like one exported by gott or imported before under another id. Running
+// intervals last until now.
+func findTimewInterval(db Database, t timewInterval, now time.Time) (*Interval, bool, error) {
+	end := t.End
+	if end.IsZero() {
+		end = now
+	}
+	if !end.After(t.Start) {
+		end = t.Start.Add(time.Second)
+	}
+	candidates, err := db.Overlapping(t.Start, end)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, c := range candidates {
+		exported := timewFromInterval(c)
+		if exported.Start.Equal(t.Start) && exported.End.Equal(t.End) {
+			return c, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// String formats the interval as line of a timewarrior data file.
+func (t timewInterval) String() string {
+	var b strings.Builder
+	b.WriteString(timewInc + " " + t.Start.Format(timewTimeFormat))
+	if !t.End.IsZero() {
+		b.WriteString(" " + timewEndSep + " " + t.End.Format(timewTimeFormat))
+	}
+	if len(t.Tags) > 0 || t.Annotation != "" {
+		b.WriteString(" " + timewTagSep)
+	}
+	for _, tag := range t.Tags {
+		b.WriteString(" " + quoteTimew(tag))
+	}
+	if t.Annotation != "" {
+		b.WriteString(" " + timewTagSep + " " + `"` + strings.ReplaceAll(t.Annotation, `"`, `\"`) + `"`)
+	}
+	return b.String()
+}
+
+// quoteTimew quotes tags which would not be read back as one tag.
+func quoteTimew(tag string) string {
+	if tag == "" || tag == timewTagSep || strings.ContainsRune(tag, '"') || strings.IndexFunc(tag, unicode.IsSpace) >= 0 {
+		return `"` + strings.ReplaceAll(tag, `"`, `\"`) + `"`
+	}
+	return tag
+}
+
+// timewExport is an interval in the json of timew export.
+type timewExport struct {
+	ID         int      `json:"id"`
+	Start      string   `json:"start"`
+	End        string   `json:"end,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Annotation string   `json:"annotation,omitempty"`
+}
+
+// writeTimewJSON writes the intervals like timew export does. The ids are
+// the numbers of the handles, @1 is the latest interval like in timewarrior.
+func writeTimewJSON(w io.Writer, intervals []*Interval, ids map[string]string) error {
+	fmt.Fprintln(w, "[")
+	for n, i := range intervals {
+		t := timewFromInterval(i)
+		e := timewExport{
+			Start:      t.Start.Format(timewTimeFormat),
+			Tags:       t.Tags,
+			Annotation: t.Annotation,
+		}
+		fmt.Sscanf(strings.TrimPrefix(ids[i.ID], HandlePrefix), "%d", &e.ID)
+		if !t.End.IsZero() {
+			e.End = t.End.Format(timewTimeFormat)
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		sep := ","
+		if n == len(intervals)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(w, "%s%s\n", data, sep)
+	}
+	_, err := fmt.Fprintln(w, "]")
+	return err
+}
+
+// writeTimewData writes the intervals into the data directory of the
+// timewarrior directory dir, one file per month. It refuses to write into a
+// data directory with month files, unless force is set. Then the files of the
+// months and the tags are replaced atomically, without backups timewarrior
+// would not expect in its data directory. It returns the written files.
+func writeTimewData(dir string, intervals []*Interval, force bool) ([]string, error) {
+	data := filepath.Join(dir, timewDataDir)
+	existing, err := filepath.Glob(filepath.Join(data, "????-??.data"))
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 && !force {
+		return nil, fmt.Errorf("%s already has timewarrior data. use --force to replace the months and tags written", data)
+	}
+	if err := os.MkdirAll(data, 0755); err != nil {
+		return nil, err
+	}
+	months := map[string][]string{}
+	tags := map[string]map[string]int{}
+	for _, i := range intervals {
+		t := timewFromInterval(i)
+		file := filepath.Join(data, t.Start.Format(timewFileFormat))
+		months[file] = append(months[file], t.String())
+		for _, tag := range t.Tags {
+			if tags[tag] == nil {
+				tags[tag] = map[string]int{"count": 0}
+			}
+			tags[tag]["count"]++
+		}
+	}
+
+	var files []string
+	for file := range months {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		if err := writeFileAtomic(file, []byte(strings.Join(months[file], "\n")+"\n"), 0644, 0); err != nil {
+			return nil, err
+		}
+	}
+	tagData, err := json.Marshal(tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(filepath.Join(data, timewTagsFile), tagData, 0644, 0); err != nil {
+		return nil, err
+	}
+	// timewarrior needs a config file
+	if config := filepath.Join(dir, timewConfigFile); !fileExists(config) {
+		if err := ioutil.WriteFile(config, nil, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,218 @@
+package gott
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimewLine(t *testing.T) {
+	start := time.Date(2022, 1, 14, 8, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	for line, expected := range map[string]timewInterval{
+		`inc 20220114T080000Z`:                                  {Start: start},
+		`inc 20220114T080000Z - 20220114T090000Z`:               {Start: start, End: end},
+		`inc 20220114T080000Z - 20220114T090000Z # a "b c"`:     {Start: start, End: end, Tags: []string{"a", "b c"}},
+		`inc 20220114T080000Z # # "say \"hi\""`:                 {Start: start, Annotation: `say "hi"`},
+		`inc 20220114T080000Z - 20220114T090000Z # a # "b # c"`: {Start: start, End: end, Tags: []string{"a"}, Annotation: "b # c"},
+	} {
+		parsed, err := parseTimewLine(line)
+		assert.NoError(t, err, line)
+		assert.Equal(t, expected, parsed, line)
+	}
+
+	for _, line := range []string{
+		`exc 20220114T080000Z`,
+		`inc 2022-01-14`,
+		`inc 20220114T080000Z - soon`,
+		`inc 20220114T080000Z tag`,
+		`inc 20220114T080000Z # a # "b" "c"`,
+		`inc 20220114T080000Z # "a`,
+	} {
+		_, err := parseTimewLine(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestTimewToInterval(t *testing.T) {
+	start := time.Date(2022, 1, 14, 8, 0, 0, 0, time.UTC)
+	i := timewInterval{
+		Start:      start,
+		End:        start.Add(time.Hour),
+		Tags:       []string{"docs", "+billable", "proj:gott.docs", "ref:ID-7", "estimate:2h", "points:many", "code review"},
+		Annotation: "fix typos",
+	}.toInterval(testUDAs)
+	assert.Equal(t, timewID(start), i.ID)
+	assert.Equal(t, "gott.docs", i.Project)
+	assert.Equal(t, "ID-7", i.Ref)
+	assert.Equal(t, []string{"docs", "billable", "points:many", "code review"}, i.Tags)
+	assert.Equal(t, map[string]interface{}{"estimate": "2h0m0s"}, i.UDA)
+	assert.Equal(t, StatusEnded, i.Status)
+	assert.NoError(t, i.Validate())
+}
+
+func TestAppImportTimewarrior(t *testing.T) {
+	app, out := newTestApp(t)
+	path := filepath.Join("testdata", "timewarrior")
+
+	runApp(t, app, "import", "timewarrior", "--dry-run", path)
+	assert.Contains(t, out.String(), "would import 5 intervals")
+	reopened, _ := NewApp(app.Config)
+	assert.NoError(t, reopened.Open(true))
+	assert.Equal(t, 0, reopened.Database.Count())
+	assert.NoError(t, reopened.Close())
+
+	out.Reset()
+	runApp(t, app, "import", "timewarrior", path)
+	assert.Contains(t, out.String(), "2022-01.data: imported 3, updated 0, skipped 0")
+	assert.Contains(t, out.String(), "2022-02.data: imported 2, updated 0, skipped 0")
+	assert.Equal(t, 5, app.Database.Count())
+
+	current, found := app.Database.GetCurrent()
+	if assert.True(t, found) {
+		assert.Equal(t, "gott", current.Project)
+	}
+	intervals, _ := app.Database.Filter([]string{KeyAll, "proj:gott.docs"})
+	if assert.Len(t, intervals, 1) {
+		assert.Equal(t, `fix a.b/c, "typos"`, intervals[0].Annotation)
+		assert.Equal(t, []string{"docs"}, intervals[0].Tags)
+	}
+
+	// a second import skips the intervals
+	out.Reset()
+	runApp(t, app, "import", "timewarrior", filepath.Join(path, "data", "2022-01.data"))
+	assert.Contains(t, out.String(), "imported 0 intervals, skipped 3 imported before")
+	assert.Equal(t, 5, app.Database.Count())
+
+	// the running interval has ended since
+	file := filepath.Join(t.TempDir(), "2022-02.data")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("inc 20220201T090000Z - 20220201T100000Z # meeting estimate:2h\n"+
+		"inc 20220202T090000Z - 20220202T113000Z # project:gott\n"), 0644))
+	out.Reset()
+	runApp(t, app, "import", "timewarrior", file)
+	assert.Contains(t, out.String(), "2022-02.data: imported 0, updated 1, skipped 1")
+	assert.Contains(t, out.String(), "imported 0 intervals, updated 1, skipped 1 imported before")
+	_, found = app.Database.GetCurrent()
+	assert.False(t, found)
+	if stopped, found := app.Database.Get(current.ID); assert.True(t, found) {
+		assert.Equal(t, time.Date(2022, 2, 2, 11, 30, 0, 0, time.UTC), stopped.End.UTC())
+		assert.Equal(t, StatusEnded, stopped.Status)
+	}
+
+	out.Reset()
+	runApp(t, app, "import", "timewarrior", file)
+	assert.Contains(t, out.String(), "imported 0 intervals, skipped 2 imported before")
+}
+
+func TestTimewFromInterval(t *testing.T) {
+	begin := time.Date(2022, 1, 14, 8, 0, 0, 0, time.UTC)
+	i := &Interval{
+		Begin:      begin,
+		End:        begin.Add(time.Hour),
+		Project:    "gott",
+		Ref:        "ID-7",
+		Tags:       []string{"docs", "code review"},
+		UDA:        map[string]interface{}{"estimate": "2h0m0s", "points": 2.5},
+		Annotation: `say "hi"`,
+	}
+	tw := timewFromInterval(i)
+	assert.Equal(t, []string{"code review", "docs", "estimate:2h0m0s", "points:2.5", "proj:gott", "ref:ID-7"}, tw.Tags)
+	assert.Equal(t, `inc 20220114T080000Z - 20220114T090000Z # "code review" docs estimate:2h0m0s points:2.5 proj:gott ref:ID-7 # "say \"hi\""`, tw.String())
+
+	// the line reads back and imports to the same attributes
+	parsed, err := parseTimewLine(tw.String())
+	assert.NoError(t, err)
+	assert.Equal(t, tw, parsed)
+	imported := parsed.toInterval(testUDAs)
+	assert.Equal(t, i.Project, imported.Project)
+	assert.Equal(t, i.Ref, imported.Ref)
+	assert.Equal(t, i.UDA, imported.UDA)
+	assert.ElementsMatch(t, i.Tags, imported.Tags)
+
+	// duration only intervals begin at the start of the day
+	day := time.Date(2022, 1, 14, 0, 0, 0, 0, time.UTC)
+	tw = timewFromInterval(&Interval{Begin: day, End: day, Duration: 90 * time.Minute, Annotation: "a"})
+	assert.Equal(t, `inc 20220114T000000Z - 20220114T013000Z # # "a"`, tw.String())
+	assert.Equal(t, "inc 20220114T000000Z", timewFromInterval(&Interval{Begin: day}).String())
+}
+
+func TestAppExportTimew(t *testing.T) {
+	app, out := newTestApp(t)
+	app.Config.Location = time.UTC
+	runApp(t, app, "track", "2022-01-14", "09:00-10:00", "--", "proj:gott", "+docs", "writing")
+	runApp(t, app, "start", "--now=2022-02-01 08:00", "--", "ref:ID-1")
+
+	out.Reset()
+	runApp(t, app, "export", "--format", "timew")
+	assert.Equal(t, `[
+{"id":2,"start":"20220114T090000Z","end":"20220114T100000Z","tags":["docs","proj:gott"],"annotation":"writing"},
+{"id":1,"start":"20220201T080000Z","tags":["ref:ID-1"]}
+]
+`, out.String())
+
+	out.Reset()
+	dir := t.TempDir()
+	runApp(t, app, "export", "--format", "timew", "--data", dir, ":all")
+	assert.Contains(t, out.String(), "exported 2 intervals into 2 files")
+	assert.FileExists(t, filepath.Join(dir, "timewarrior.cfg"))
+	assert.FileExists(t, filepath.Join(dir, "data", "tags.data"))
+
+	// existing month files are not clobbered without --force
+	january := filepath.Join(dir, "data", "2022-01.data")
+	december := filepath.Join(dir, "data", "2021-12.data")
+	assert.NoError(t, ioutil.WriteFile(january, []byte("inc 20220101T080000Z - 20220101T090000Z\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(december, []byte("inc 20211201T080000Z - 20211201T090000Z\n"), 0644))
+	intervals, _ := app.Database.Filter([]string{KeyAll})
+	_, err := writeTimewData(dir, intervals, false)
+	assert.Error(t, err)
+	data, _ := ioutil.ReadFile(january)
+	assert.Equal(t, "inc 20220101T080000Z - 20220101T090000Z\n", string(data))
+	runApp(t, app, "export", "--format", "timew", "--data", dir, "--force", ":all")
+	data, _ = ioutil.ReadFile(january)
+	assert.Contains(t, string(data), "proj:gott")
+	data, _ = ioutil.ReadFile(december)
+	assert.Equal(t, "inc 20211201T080000Z - 20211201T090000Z\n", string(data))
+	backups, _ := filepath.Glob(filepath.Join(dir, "data", "*"+backupSuffix+"*"))
+	assert.Empty(t, backups)
+
+	// the exported data imports again, with the month it kept
+	other, out := newTestApp(t)
+	runApp(t, other, "import", "timewarrior", dir)
+	assert.Contains(t, out.String(), "imported 3 intervals")
+	intervals, _ = other.Database.Filter([]string{KeyAll, "proj:gott"})
+	if assert.Len(t, intervals, 1) {
+		assert.Equal(t, []string{"docs"}, intervals[0].Tags)
+		assert.Equal(t, "writing", intervals[0].Annotation)
+	}
+}
+
+func TestAppImportTimewExported(t *testing.T) {
+	app, out := newTestApp(t)
+	app.Config.Location = time.UTC
+	runApp(t, app, "track", "2022-01-14", "09:00-10:00", "--", "proj:gott")
+	runApp(t, app, "track", "2022-01-15", "90m", "--", "reading")
+	runApp(t, app, "start", "--now=2022-02-01 08:00", "--", "ref:ID-1")
+	dir := t.TempDir()
+	runApp(t, app, "export", "--format", "timew", "--data", dir, ":all")
+
+	// the exported intervals are found by their start and end
+	out.Reset()
+	runApp(t, app, "import", "timewarrior", dir)
+	assert.Contains(t, out.String(), "imported 0 intervals, skipped 3 imported before")
+	assert.Equal(t, 3, app.Database.Count())
+
+	// overlapping intervals are imported only with --force
+	file := filepath.Join(t.TempDir(), "2022-01.data")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("inc 20220114T093000Z - 20220114T103000Z # meeting\n"), 0644))
+	interval := timewInterval{Start: time.Date(2022, 1, 14, 9, 30, 0, 0, time.UTC), End: time.Date(2022, 1, 14, 10, 30, 0, 0, time.UTC)}.toInterval(testUDAs)
+	overlapping, err := findOverlaps(app.Database, &interval, app.clock().Now())
+	assert.NoError(t, err)
+	assert.Len(t, overlapping, 1)
+	out.Reset()
+	runApp(t, app, "import", "timewarrior", "--force", file)
+	assert.Contains(t, out.String(), "2022-01.data: imported 1, updated 0, skipped 0")
+	assert.Equal(t, 4, app.Database.Count())
+}
@@ -0,0 +1,268 @@
+package gott
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ConfUDA = "uda"
+
+const (
+	UDATypeString   = "string"
+	UDATypeNumber   = "number"
+	UDATypeDuration = "duration"
+	UDATypeDate     = "date"
+	UDATypeEnum     = "enum"
+)
+
+var UDATypes = []string{UDATypeString, UDATypeNumber, UDATypeDuration, UDATypeDate, UDATypeEnum}
+
+// UDASep separates the name of an uda from its value like in estimate:2h.
+const UDASep = ":"
+
+var udaNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// UDA is a user defined attribute declared in the config. Its values are
+// stored in Interval.UDA as float64 for numbers and as string otherwise:
+// durations like 1h30m0s and dates like 2022-01-14.
+type UDA struct {
+	Name string
+	Type string
+	// Values are the allowed values of an enum
+	Values []string
+	// Default is the value of new intervals. It is empty for none
+	Default string
+}
+
+// UDAs are the declared udas sorted by name.
+type UDAs []UDA
+
+// Get returns the uda with the name.
+func (u UDAs) Get(name string) (UDA, bool) {
+	for _, uda := range u {
+		if uda.Name == name {
+			return uda, true
+		}
+	}
+	return UDA{}, false
+}
+
+// lex returns the uda and its value text if part is NAME:VALUE of a declared
+// uda.
+func (u UDAs) lex(part string) (UDA, string, bool) {
+	idx := strings.Index(part, UDASep)
+	if idx <= 0 {
+		return UDA{}, "", false
+	}
+	uda, found := u.Get(part[:idx])
+	return uda, part[idx+len(UDASep):], found
+}
+
+// setDefaults sets the default value of the udas the interval does not have.
+func (u UDAs) setDefaults(i *Interval) {
+	for _, uda := range u {
+		if uda.Default == "" {
+			continue
+		}
+		if _, found := i.UDA[uda.Name]; found {
+			continue
+		}
+		// defaults are checked when the config is read
+		if value, err := uda.Parse(uda.Default); err == nil {
+			setUDA(i, uda.Name, value)
+		}
+	}
+}
+
+// Validate checks the values of the declared udas of the interval have their
+// type.
+func (u UDAs) Validate(i *Interval) error {
+	for name, value := range i.UDA {
+		uda, found := u.Get(name)
+		if !found {
+			continue
+		}
+		text, ok := value.(string)
+		if number, isNumber := value.(float64); isNumber && uda.Type == UDATypeNumber {
+			text, ok = strconv.FormatFloat(number, 'f', -1, 64), true
+		}
+		if !ok {
+			return fmt.Errorf("interval %s has the invalid %s %v", i.ID, name, value)
+		}
+		if _, err := uda.Parse(text); err != nil {
+			return fmt.Errorf("interval %s: %s", i.ID, err.Error())
+		}
+	}
+	return nil
+}
+
+// Parse parses the text into the value stored in the interval.
+func (u UDA) Parse(text string) (interface{}, error) {
+	switch u.Type {
+	case UDATypeNumber:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number, got %s", u.Name, text)
+		}
+		return f, nil
+	case UDATypeDuration:
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a duration like 1h30m, got %s", u.Name, text)
+		}
+		return d.String(), nil
+	case UDATypeDate:
+		d, err := time.Parse(dateFormat, text)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a date like YYYY-MM-DD, got %s", u.Name, text)
+		}
+		return d.Format(dateFormat), nil
+	case UDATypeEnum:
+		if !containsString(u.Values, text) {
+			return nil, fmt.Errorf("%s must be one of %s, got %s", u.Name, strings.Join(u.Values, ", "), text)
+		}
+		return text, nil
+	}
+	return text, nil
+}
+
+// compare compares the values of the uda. ok is false if they can not be
+// compared.
+func (u UDA) compare(a, b interface{}) (result int, ok bool) {
+	switch u.Type {
+	case UDATypeNumber:
+		x, okA := a.(float64)
+		y, okB := b.(float64)
+		if !okA || !okB {
+			return 0, false
+		}
+		return compareFloat(x, y), true
+	case UDATypeDuration:
+		textA, okA := a.(string)
+		textB, okB := b.(string)
+		if !okA || !okB {
+			return 0, false
+		}
+		x, errA := time.ParseDuration(textA)
+		y, errB := time.ParseDuration(textB)
+		if errA != nil || errB != nil {
+			return 0, false
+		}
+		return compareFloat(float64(x), float64(y)), true
+	}
+	x, okA := a.(string)
+	y, okB := b.(string)
+	if !okA || !okB {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+func compareFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// fmtUDAValue formats the stored value like it is entered on the command
+// line.
+func fmtUDAValue(value interface{}) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+func setUDA(i *Interval, name string, value interface{}) {
+	if i.UDA == nil {
+		i.UDA = make(map[string]interface{})
+	}
+	i.UDA[name] = value
+}
+
+// sortedUDANames returns the names of the udas of the interval sorted.
+func sortedUDANames(i *Interval) []string {
+	var names []string
+	for name := range i.UDA {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readUDAs reads the udas declared in the config like
+//
+//	uda:
+//	  estimate:
+//	    type: duration
+//	    default: 1h
+//	  client:
+//	    type: enum
+//	    values: [acme, initech]
+//
+// Invalid udas are skipped and returned as error.
+func readUDAs(get func(key string) interface{}) (UDAs, error) {
+	declared, _ := get(ConfUDA).(map[string]interface{})
+	var udas UDAs
+	var errs []string
+	for name := range declared {
+		key := ConfUDA + "." + name + "."
+		uda := UDA{
+			Name:    name,
+			Type:    strings.ToLower(fmt.Sprint(valueOr(get(key+"type"), UDATypeString))),
+			Default: fmt.Sprint(valueOr(get(key+"default"), "")),
+		}
+		if values, ok := get(key + "values").([]interface{}); ok {
+			for _, v := range values {
+				uda.Values = append(uda.Values, fmt.Sprint(v))
+			}
+		}
+		if err := uda.check(); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		udas = append(udas, uda)
+	}
+	sort.Slice(udas, func(i, j int) bool { return udas[i].Name < udas[j].Name })
+	sort.Strings(errs)
+	if len(errs) > 0 {
+		return udas, fmt.Errorf("invalid %s: %s", ConfUDA, strings.Join(errs, "; "))
+	}
+	return udas, nil
+}
+
+func valueOr(value, fallback interface{}) interface{} {
+	if value == nil {
+		return fallback
+	}
+	return value
+}
+
+// check checks the declaration of the uda.
+func (u UDA) check() error {
+	reserved := []string{strings.TrimSuffix(ProjectPrefix, UDASep), strings.TrimSuffix(ProjectPrefixShort, UDASep),
+		strings.TrimSuffix(RefPrefix, UDASep), strings.TrimSuffix(editUDAPrefix, UDASep)}
+	if !udaNameRegexp.MatchString(u.Name) || containsString(reserved, u.Name) {
+		return fmt.Errorf("%s is not a valid name", u.Name)
+	}
+	if !containsString(UDATypes, u.Type) {
+		return fmt.Errorf("%s has the unknown type %s. use one of %s", u.Name, u.Type, strings.Join(UDATypes, ", "))
+	}
+	if u.Type == UDATypeEnum && len(u.Values) == 0 {
+		return fmt.Errorf("%s is an enum without values", u.Name)
+	}
+	if u.Default != "" {
+		if _, err := u.Parse(u.Default); err != nil {
+			return fmt.Errorf("invalid default: %s", err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package gott
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testUDAs = UDAs{
+	{Name: "client", Type: UDATypeEnum, Values: []string{"acme", "initech"}, Default: "acme"},
+	{Name: "due", Type: UDATypeDate},
+	{Name: "estimate", Type: UDATypeDuration},
+	{Name: "note", Type: UDATypeString},
+	{Name: "points", Type: UDATypeNumber},
+}
+
+func TestReadUDAs(t *testing.T) {
+	config := map[string]interface{}{
+		"uda": map[string]interface{}{
+			"estimate": map[string]interface{}{"type": "duration", "default": "1h"},
+			"client":   map[string]interface{}{"type": "enum", "values": []interface{}{"acme", "initech"}},
+			"note":     map[string]interface{}{},
+			"proj":     map[string]interface{}{"type": "string"},
+			"size":     map[string]interface{}{"type": "shoe"},
+			"points":   map[string]interface{}{"type": "number", "default": "many"},
+		},
+	}
+	get := func(key string) interface{} {
+		var value interface{} = config
+		for _, part := range strings.Split(key, ".") {
+			m, _ := value.(map[string]interface{})
+			value = m[part]
+		}
+		return value
+	}
+
+	udas, err := readUDAs(get)
+	assert.Equal(t, UDAs{
+		{Name: "client", Type: UDATypeEnum, Values: []string{"acme", "initech"}},
+		{Name: "estimate", Type: UDATypeDuration, Default: "1h"},
+		{Name: "note", Type: UDATypeString},
+	}, udas)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "proj is not a valid name")
+		assert.Contains(t, err.Error(), "size has the unknown type shoe")
+		assert.Contains(t, err.Error(), "points must be a number")
+	}
+}
+
+func TestLexUDAs(t *testing.T) {
+	i := &Interval{}
+	assert.NoError(t, lexInterval(strings.Fields("review estimate:90m points:2.5 due:2022-02-01 note:x:y http://example.com"), i, testUDAs))
+	assert.Equal(t, map[string]interface{}{"estimate": "1h30m0s", "points": 2.5, "due": "2022-02-01", "note": "x:y"}, i.UDA)
+	assert.Equal(t, "review http://example.com", i.Annotation)
+
+	testUDAs.setDefaults(i)
+	assert.Equal(t, "acme", i.UDA["client"])
+	assert.NoError(t, testUDAs.Validate(i))
+	assert.Equal(t, "review http://example.com client:acme due:2022-02-01 estimate:1h30m0s note:x:y points:2.5", formatRaw(i))
+
+	for _, arg := range []string{"estimate:soon", "points:x", "due:tomorrow", "client:umbrella"} {
+		assert.Error(t, lexInterval([]string{arg}, &Interval{}, testUDAs), arg)
+	}
+	// undeclared udas are text
+	assert.NoError(t, lexInterval([]string{"estimate:soon"}, i, nil))
+	assert.Equal(t, "estimate:soon", i.Annotation)
+
+	i.UDA["points"] = "many"
+	assert.Error(t, testUDAs.Validate(i))
+}
+
+func TestFilterUDAs(t *testing.T) {
+	db := NewDatabaseJson("", Calendar{Clock: SystemClock{}})
+	db.udas = testUDAs
+	for _, raw := range []string{"a estimate:30m points:1", "b estimate:2h points:3", "c estimate:4h", "d"} {
+		i := NewInterval(nil)
+		assert.NoError(t, lexInterval(strings.Fields(raw), &i, testUDAs))
+		i.Begin = time.Date(2022, 1, 14, 9, 0, 0, 0, time.UTC)
+		i.End = i.Begin.Add(time.Hour)
+		assert.NoError(t, db.Append(i))
+	}
+
+	for filter, expected := range map[string]string{
+		"estimate:120m":      "b",
+		"estimate:1h..4h":    "b c",
+		"estimate:..1h":      "a",
+		"points:2..":         "b",
+		"estimate:":          "d",
+		"not points: :all":   "a b",
+		"client:acme or a":   "a",
+		"note:x estimate:2h": "",
+	} {
+		intervals, err := db.Filter(strings.Fields(filter))
+		if !assert.NoError(t, err, filter) {
+			continue
+		}
+		var annotations []string
+		for _, i := range intervals {
+			annotations = append(annotations, i.Annotation)
+		}
+		assert.Equal(t, expected, strings.Join(annotations, " "), filter)
+	}
+
+	for _, filter := range []string{"estimate:soon", "client:a..b", "note:a..b"} {
+		_, err := db.Filter([]string{filter})
+		assert.Error(t, err, filter)
+	}
+}
+
+func TestModificationUDAs(t *testing.T) {
+	i := &Interval{UDA: map[string]interface{}{"estimate": "1h0m0s", "points": 1.0}}
+	m, err := lexModification([]string{"estimate:2h", "points:", "due:2022-02-01"}, testUDAs)
+	assert.NoError(t, err)
+	m.applyTo(i)
+	assert.Equal(t, map[string]interface{}{"estimate": "2h0m0s", "due": "2022-02-01"}, i.UDA)
+	assert.Equal(t, "due:2022-02-01 estimate:2h0m0s", i.Raw)
+
+	_, err = lexModification([]string{"points:many"}, testUDAs)
+	assert.Error(t, err)
+}